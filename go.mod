@@ -4,6 +4,7 @@ go 1.23.0
 
 require (
 	github.com/akamensky/argparse v1.4.0
+	github.com/eclipse/paho.mqtt.golang v1.5.0
 	github.com/hoegaarden/go-bthome v0.0.0-20241216181511-331784d2336d
 	github.com/rs/zerolog v1.31.0
 	gopkg.in/yaml.v3 v3.0.1
@@ -16,6 +17,7 @@ require (
 	github.com/ghostiam/binstruct v1.4.0 // indirect
 	github.com/go-ole/go-ole v1.3.0 // indirect
 	github.com/godbus/dbus/v5 v5.1.0 // indirect
+	github.com/gorilla/websocket v1.5.3 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/saltosystems/winrt-go v0.0.0-20241030114511-98be01919aa6 // indirect
@@ -26,6 +28,8 @@ require (
 	github.com/tinygo-org/pio v0.2.0 // indirect
 	gitlab.com/go-extension/aes-ccm v0.0.0-20230221065045-e58665ef23c7 // indirect
 	golang.org/x/exp v0.0.0-20241204233417-43b7b7cde48d // indirect
+	golang.org/x/net v0.27.0 // indirect
+	golang.org/x/sync v0.10.0 // indirect
 	golang.org/x/sys v0.28.0 // indirect
 )
 
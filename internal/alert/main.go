@@ -0,0 +1,203 @@
+// Package alert evaluates alert rules against climate state and sends
+// de-duplicated notifications, with a recovery message when a condition clears.
+package alert
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/climate"
+	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+const SEVERITY_INFO = "info"
+const SEVERITY_WARNING = "warning"
+const SEVERITY_CRITICAL = "critical"
+
+const STATUS_FIRING = "firing"
+const STATUS_RESOLVED = "resolved"
+
+// Notification is sent to sinks when alert fires, repeats or resolves
+type Notification struct {
+	Rule     string    `json:"rule"`
+	Key      string    `json:"key"` // rule or rule:instance
+	Type     string    `json:"type"`
+	Severity string    `json:"severity"`
+	Status   string    `json:"status"`
+	Message  string    `json:"message"`
+	Value    float64   `json:"value"`
+	Since    time.Time `json:"since"` // condition first seen
+	Time     time.Time `json:"time"`
+}
+
+func (n Notification) Title() string {
+	if n.Status == STATUS_RESOLVED {
+		return fmt.Sprintf("Resolved: %s", n.Rule)
+	}
+	return fmt.Sprintf("Alert: %s", n.Rule)
+}
+
+type Sink interface {
+	Name() string
+	Send(n Notification) error
+}
+
+// alertState tracks one active condition
+type alertState struct {
+	rule     rule
+	since    time.Time // condition first seen
+	firing   bool
+	notified time.Time
+	last     finding
+}
+
+type Engine struct {
+	climate   climate.Climate
+	rules     []rule
+	sinks     []Sink
+	interval  time.Duration
+	repeat    time.Duration
+	batteries func() map[string]float64
+
+	mu       sync.Mutex
+	states   map[string]*alertState
+	stopChan chan struct{}
+}
+
+// New creates engine from config, returns nil if no rules are configured.
+// mqtt sinks are added by caller with AddSink, see NewMQTTSink.
+func New(cfg *config.Config, c climate.Climate) *Engine {
+	if len(cfg.Alerts.Rules) == 0 {
+		return nil
+	}
+	interval := time.Duration(cfg.Alerts.Interval * float64(time.Second))
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	e := &Engine{
+		climate:  c,
+		interval: interval,
+		repeat:   time.Duration(cfg.Alerts.Repeat * float64(time.Minute)),
+		states:   map[string]*alertState{},
+		stopChan: make(chan struct{}),
+	}
+	for _, ruleConfig := range cfg.Alerts.Rules {
+		r, err := newRule(ruleConfig)
+		if err != nil {
+			log.Error().Err(err).Msg("Ignoring alert rule")
+			continue
+		}
+		e.rules = append(e.rules, r)
+	}
+	for _, sinkConfig := range cfg.Alerts.Sinks {
+		sink, err := newSink(sinkConfig)
+		if err != nil {
+			log.Error().Err(err).Msg("Ignoring alert sink")
+			continue
+		}
+		if sink != nil {
+			e.sinks = append(e.sinks, sink)
+		}
+	}
+	return e
+}
+
+func (e *Engine) AddSink(sink Sink) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sinks = append(e.sinks, sink)
+}
+
+// SetBatteries sets function returning battery percent of sensors by name
+func (e *Engine) SetBatteries(batteries func() map[string]float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.batteries = batteries
+}
+
+func (e *Engine) Start() {
+	log.Info().Msgf("Starting alert engine with %d rules and %d sinks", len(e.rules), len(e.sinks))
+	go func() {
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				e.evaluate(time.Now())
+			case <-e.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+func (e *Engine) Stop() {
+	close(e.stopChan)
+}
+
+// evaluate checks all rules and notifies on changes
+func (e *Engine) evaluate(now time.Time) {
+	e.mu.Lock()
+	batteries := map[string]float64{}
+	if e.batteries != nil {
+		batteries = e.batteries()
+	}
+	notifications := []Notification{}
+	active := map[string]bool{}
+	for _, r := range e.rules {
+		for _, f := range r.check(e.climate, batteries) {
+			active[f.key] = true
+			state, ok := e.states[f.key]
+			if !ok {
+				state = &alertState{rule: r, since: now}
+				e.states[f.key] = state
+			}
+			state.last = f
+			switch {
+			case !state.firing && now.Sub(state.since) >= r.hold:
+				state.firing = true
+				state.notified = now
+				notifications = append(notifications, state.notification(f.key, STATUS_FIRING, now))
+			case state.firing && e.repeat > 0 && now.Sub(state.notified) >= e.repeat:
+				state.notified = now
+				notifications = append(notifications, state.notification(f.key, STATUS_FIRING, now))
+			}
+		}
+	}
+	for key, state := range e.states {
+		if active[key] {
+			continue
+		}
+		if state.firing {
+			notifications = append(notifications, state.notification(key, STATUS_RESOLVED, now))
+		}
+		delete(e.states, key)
+	}
+	sinks := e.sinks
+	e.mu.Unlock()
+
+	for _, n := range notifications {
+		log.Warn().Msgf("%s: %s", n.Title(), n.Message)
+		for _, sink := range sinks {
+			if err := sink.Send(n); err != nil {
+				log.Error().Err(err).Msgf("Failed to send alert %s to %s", n.Key, sink.Name())
+			}
+		}
+	}
+}
+
+func (s *alertState) notification(key, status string, now time.Time) Notification {
+	return Notification{
+		Rule:     s.rule.name,
+		Key:      key,
+		Type:     s.rule.kind,
+		Severity: s.rule.severity,
+		Status:   status,
+		Message:  s.last.message,
+		Value:    s.last.value,
+		Since:    s.since,
+		Time:     now,
+	}
+}
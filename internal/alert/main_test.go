@@ -0,0 +1,77 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/climate"
+	"github.com/ksimuk/ebus-climate/internal/config"
+)
+
+type stubClimate struct {
+	climate.Climate
+	pressure float64
+	boiler   string
+}
+
+func (s *stubClimate) GetStat() climate.Stat { return climate.Stat{WaterPressure: s.pressure} }
+func (s *stubClimate) GetError() string      { return s.boiler }
+func (s *stubClimate) Health() climate.Health {
+	return climate.Health{Components: []climate.ComponentHealth{{Name: climate.COMPONENT_EBUSD, Status: climate.HEALTH_OK}}}
+}
+
+type recordSink struct {
+	sent []Notification
+}
+
+func (s *recordSink) Name() string              { return "record" }
+func (s *recordSink) Send(n Notification) error { s.sent = append(s.sent, n); return nil }
+
+func TestEngine(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Alerts.Repeat = 30
+	cfg.Alerts.Rules = []config.AlertRule{
+		{Type: RULE_PRESSURE_LOW, For: 5, Severity: SEVERITY_CRITICAL},
+		{Type: RULE_BOILER_ERROR},
+		{Type: RULE_BATTERY_LOW},
+	}
+	c := &stubClimate{pressure: 0.8}
+	e := New(cfg, c)
+	sink := &recordSink{}
+	e.AddSink(sink)
+	e.SetBatteries(func() map[string]float64 { return map[string]float64{"living": 15, "outside": -1} })
+
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	e.evaluate(now)
+	// battery fires at once, pressure waits for hold time
+	if len(sink.sent) != 1 || sink.sent[0].Key != "battery_low:living" {
+		t.Fatalf("expected battery alert only, got %+v", sink.sent)
+	}
+	e.evaluate(now.Add(5 * time.Minute))
+	if len(sink.sent) != 2 || sink.sent[1].Rule != RULE_PRESSURE_LOW || sink.sent[1].Severity != SEVERITY_CRITICAL {
+		t.Fatalf("expected pressure alert, got %+v", sink.sent)
+	}
+	// no duplicates until repeat interval
+	e.evaluate(now.Add(10 * time.Minute))
+	if len(sink.sent) != 2 {
+		t.Fatalf("expected no duplicate notifications, got %+v", sink.sent)
+	}
+	e.evaluate(now.Add(31 * time.Minute))
+	if len(sink.sent) != 3 || sink.sent[2].Key != "battery_low:living" {
+		t.Fatalf("expected repeated battery alert, got %+v", sink.sent)
+	}
+
+	c.pressure = 1.5
+	c.boiler = "F28"
+	e.evaluate(now.Add(32 * time.Minute))
+	if len(sink.sent) != 5 {
+		t.Fatalf("expected recovery and boiler error, got %+v", sink.sent)
+	}
+	statuses := map[string]string{}
+	for _, n := range sink.sent[3:] {
+		statuses[n.Key] = n.Status
+	}
+	if statuses[RULE_PRESSURE_LOW] != STATUS_RESOLVED || statuses[RULE_BOILER_ERROR] != STATUS_FIRING {
+		t.Errorf("unexpected notifications %+v", sink.sent[3:])
+	}
+}
@@ -0,0 +1,127 @@
+package alert
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/climate"
+	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/ksimuk/ebus-climate/internal/vailant"
+)
+
+const RULE_PRESSURE_LOW = "pressure_low"
+const RULE_COMFORT = "comfort"
+const RULE_EBUSD_UNREACHABLE = "ebusd_unreachable"
+const RULE_BOILER_ERROR = "boiler_error"
+const RULE_BATTERY_LOW = "battery_low"
+const RULE_EFFICIENCY_REGRESSION = "efficiency_regression"
+
+const DEFAULT_PRESSURE_THRESHOLD = 1.0 // bar
+const DEFAULT_COMFORT_THRESHOLD = 1.5  // degrees below target
+const DEFAULT_BATTERY_THRESHOLD = 20.0 // percent
+
+// finding is an active condition, key separates instances of a rule such as sensors
+type finding struct {
+	key     string
+	message string
+	value   float64
+}
+
+type rule struct {
+	name      string
+	kind      string
+	threshold float64
+	hold      time.Duration
+	severity  string
+}
+
+func newRule(cfg config.AlertRule) (rule, error) {
+	r := rule{
+		name:      cfg.Name,
+		kind:      cfg.Type,
+		threshold: cfg.Threshold,
+		hold:      time.Duration(cfg.For * float64(time.Minute)),
+		severity:  cfg.Severity,
+	}
+	if r.name == "" {
+		r.name = r.kind
+	}
+	if r.severity == "" {
+		r.severity = SEVERITY_WARNING
+	}
+	if r.severity != SEVERITY_INFO && r.severity != SEVERITY_WARNING && r.severity != SEVERITY_CRITICAL {
+		return r, fmt.Errorf("unknown severity %q of alert %s", r.severity, r.name)
+	}
+	switch r.kind {
+	case RULE_PRESSURE_LOW:
+		if r.threshold == 0 {
+			r.threshold = DEFAULT_PRESSURE_THRESHOLD
+		}
+	case RULE_COMFORT:
+		if r.threshold == 0 {
+			r.threshold = DEFAULT_COMFORT_THRESHOLD
+		}
+	case RULE_BATTERY_LOW:
+		if r.threshold == 0 {
+			r.threshold = DEFAULT_BATTERY_THRESHOLD
+		}
+	case RULE_EBUSD_UNREACHABLE, RULE_BOILER_ERROR, RULE_EFFICIENCY_REGRESSION:
+	default:
+		return r, fmt.Errorf("unknown alert type %q", r.kind)
+	}
+	return r, nil
+}
+
+// check returns active findings of rule
+func (r rule) check(c climate.Climate, batteries map[string]float64) []finding {
+	switch r.kind {
+	case RULE_PRESSURE_LOW:
+		// pressure is unknown without boiler reads, covered by ebusd_unreachable
+		if ebusd, ok := c.Health().Component(climate.COMPONENT_EBUSD); ok && ebusd.Status == climate.HEALTH_FAILED {
+			return nil
+		}
+		pressure := c.GetStat().WaterPressure
+		if pressure < r.threshold {
+			return []finding{{r.name, fmt.Sprintf("Water pressure %.2f bar is below %.2f bar", pressure, r.threshold), pressure}}
+		}
+	case RULE_COMFORT:
+		if c.GetMode() != vailant.MODE_HEATING {
+			return nil
+		}
+		if inside, ok := c.Health().Component(climate.COMPONENT_INSIDE_SENSOR); ok && inside.Status != climate.HEALTH_OK {
+			return nil
+		}
+		temp, target := c.GetInsideTemp(), c.GetTargetTemperature()
+		if target-temp > r.threshold {
+			return []finding{{r.name, fmt.Sprintf("Inside temperature %.1f°C is %.1f below target %.1f°C", temp, target-temp, target), temp}}
+		}
+	case RULE_EBUSD_UNREACHABLE:
+		if ebusd, ok := c.Health().Component(climate.COMPONENT_EBUSD); ok && ebusd.Status == climate.HEALTH_FAILED {
+			return []finding{{r.name, "ebusd unreachable: " + ebusd.Message, ebusd.AgeSeconds}}
+		}
+	case RULE_BOILER_ERROR:
+		if code := c.GetError(); code != "" {
+			return []finding{{r.name, "Boiler reports error " + code, 0}}
+		}
+	case RULE_BATTERY_LOW:
+		names := make([]string, 0, len(batteries))
+		for name := range batteries {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		findings := []finding{}
+		for _, name := range names {
+			// battery level is negative or 0 when not reported
+			if level := batteries[name]; level > 0 && level < r.threshold {
+				findings = append(findings, finding{r.name + ":" + name, fmt.Sprintf("Battery of sensor %s is %.0f%%", name, level), level})
+			}
+		}
+		return findings
+	case RULE_EFFICIENCY_REGRESSION:
+		if stat := c.GetStat(); stat.EfficiencyRegression {
+			return []finding{{r.name, fmt.Sprintf("Heating used %.2f kWh per degree-day, baseline %.2f", stat.KWhPerDegreeDay, stat.EfficiencyBaseline), stat.KWhPerDegreeDay}}
+		}
+	}
+	return nil
+}
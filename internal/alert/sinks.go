@@ -0,0 +1,252 @@
+package alert
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/config"
+)
+
+const SINK_WEBHOOK = "webhook"
+const SINK_NTFY = "ntfy"
+const SINK_GOTIFY = "gotify"
+const SINK_SMTP = "smtp"
+const SINK_MQTT = "mqtt"
+
+const DEFAULT_MQTT_TOPIC = "alerts"
+const SEND_TIMEOUT = 10 * time.Second
+
+var httpClient = &http.Client{Timeout: SEND_TIMEOUT}
+
+// newSink creates sink from config, mqtt sinks need broker connection and return nil
+func newSink(cfg config.AlertSink) (Sink, error) {
+	switch cfg.Type {
+	case SINK_WEBHOOK:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("webhook sink requires url")
+		}
+		return &webhookSink{url: cfg.URL}, nil
+	case SINK_NTFY:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("ntfy sink requires url")
+		}
+		return &ntfySink{url: cfg.URL, token: cfg.Token}, nil
+	case SINK_GOTIFY:
+		if cfg.URL == "" || cfg.Token == "" {
+			return nil, fmt.Errorf("gotify sink requires url and token")
+		}
+		return &gotifySink{url: cfg.URL, token: cfg.Token}, nil
+	case SINK_SMTP:
+		if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+			return nil, fmt.Errorf("smtp sink requires host, from and to")
+		}
+		port := cfg.Port
+		if port == 0 {
+			port = 25
+		}
+		return &smtpSink{host: cfg.Host, port: port, username: cfg.Username, password: cfg.Password, from: cfg.From, to: cfg.To}, nil
+	case SINK_MQTT:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown alert sink type %q", cfg.Type)
+}
+
+// MQTTTopic returns topic of first mqtt sink in config, empty if there is none
+func MQTTTopic(cfg *config.Config) string {
+	for _, sink := range cfg.Alerts.Sinks {
+		if sink.Type == SINK_MQTT {
+			if sink.Topic == "" {
+				return DEFAULT_MQTT_TOPIC
+			}
+			return sink.Topic
+		}
+	}
+	return ""
+}
+
+func post(req *http.Request) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// webhookSink posts notification as JSON
+type webhookSink struct {
+	url string
+}
+
+func (s *webhookSink) Name() string { return SINK_WEBHOOK }
+
+func (s *webhookSink) Send(n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return post(req)
+}
+
+// ntfySink publishes to ntfy topic URL
+type ntfySink struct {
+	url   string
+	token string
+}
+
+func (s *ntfySink) Name() string { return SINK_NTFY }
+
+func (s *ntfySink) Send(n Notification) error {
+	req, err := http.NewRequest(http.MethodPost, s.url, strings.NewReader(n.Message))
+	if err != nil {
+		return err
+	}
+	priority, tag := "3", "information_source"
+	switch {
+	case n.Status == STATUS_RESOLVED:
+		priority, tag = "3", "white_check_mark"
+	case n.Severity == SEVERITY_CRITICAL:
+		priority, tag = "5", "rotating_light"
+	case n.Severity == SEVERITY_WARNING:
+		priority, tag = "4", "warning"
+	}
+	req.Header.Set("Title", n.Title())
+	req.Header.Set("Priority", priority)
+	req.Header.Set("Tags", tag)
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	return post(req)
+}
+
+// gotifySink posts to gotify message endpoint
+type gotifySink struct {
+	url   string
+	token string
+}
+
+func (s *gotifySink) Name() string { return SINK_GOTIFY }
+
+func (s *gotifySink) Send(n Notification) error {
+	priority := 2
+	if n.Status == STATUS_FIRING {
+		switch n.Severity {
+		case SEVERITY_CRITICAL:
+			priority = 8
+		case SEVERITY_WARNING:
+			priority = 5
+		}
+	}
+	body, err := json.Marshal(map[string]any{
+		"title":    n.Title(),
+		"message":  n.Message,
+		"priority": priority,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", s.token)
+	return post(req)
+}
+
+// smtpSink sends plain text email
+type smtpSink struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func (s *smtpSink) Name() string { return SINK_SMTP }
+
+// Send works like smtp.SendMail, with timeout for whole exchange so unresponsive server can't stall alerts
+func (s *smtpSink) Send(n Notification) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(s.host, fmt.Sprint(s.port)), SEND_TIMEOUT)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(SEND_TIMEOUT)); err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return err
+		}
+	}
+	if s.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(s.from); err != nil {
+		return err
+	}
+	for _, to := range s.to {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: [ebus-climate] %s\r\nDate: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		s.from, strings.Join(s.to, ", "), n.Title(), n.Time.Format(time.RFC1123Z), n.Message)
+	if _, err := w.Write([]byte(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// mqttSink publishes notification JSON using MQTT bridge
+type mqttSink struct {
+	publish func(payload []byte)
+}
+
+// NewMQTTSink creates sink calling publish with notification JSON
+func NewMQTTSink(publish func(payload []byte)) Sink {
+	return &mqttSink{publish: publish}
+}
+
+func (s *mqttSink) Name() string { return SINK_MQTT }
+
+func (s *mqttSink) Send(n Notification) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	s.publish(payload)
+	return nil
+}
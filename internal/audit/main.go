@@ -0,0 +1,110 @@
+// Package audit keeps an append-only JSON lines log of state changing actions:
+// who did what from where, with values before and after the change.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+const AUTH_MQTT = "mqtt" // command received on MQTT topic
+
+// Entry is one recorded action
+type Entry struct {
+	Time      time.Time      `json:"time"`
+	Principal string         `json:"principal"`       // token name, user, certificate name or anonymous
+	Auth      string         `json:"auth"`            // authentication method: token, basic, client_cert, none or mqtt
+	Source    string         `json:"source"`          // remote address or broker
+	Endpoint  string         `json:"endpoint"`        // method and path, or MQTT topic
+	Old       map[string]any `json:"old,omitempty"`   // values before the action
+	New       map[string]any `json:"new,omitempty"`   // requested values
+	Error     string         `json:"error,omitempty"` // set if action failed
+}
+
+// Filter selects entries, empty fields match everything
+type Filter struct {
+	From      time.Time
+	To        time.Time
+	Principal string
+	Endpoint  string
+}
+
+func (f Filter) match(entry Entry) bool {
+	if entry.Time.Before(f.From) || entry.Time.After(f.To) {
+		return false
+	}
+	if f.Principal != "" && entry.Principal != f.Principal {
+		return false
+	}
+	return f.Endpoint == "" || entry.Endpoint == f.Endpoint
+}
+
+type Log struct {
+	filePath string
+	mu       sync.Mutex
+}
+
+// New creates log writing to filePath, nil if audit log is disabled
+func New(filePath string) *Log {
+	if filePath == "" {
+		return nil
+	}
+	return &Log{filePath: filePath}
+}
+
+// Append adds entry to the end of the log, entries are never rewritten
+func (l *Log) Append(entry Entry) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.OpenFile(l.filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// Query returns entries matching filter, oldest first
+func (l *Log) Query(filter Filter) ([]Entry, error) {
+	entries := []Entry{}
+	if l == nil {
+		return entries, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.Open(l.filePath)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if filter.match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
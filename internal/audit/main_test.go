@@ -0,0 +1,45 @@
+package audit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLog(t *testing.T) {
+	l := New(filepath.Join(t.TempDir(), "audit.jsonl"))
+	start := time.Date(2026, 1, 10, 8, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Time: start, Principal: "alice", Endpoint: "POST /set", Old: map[string]any{"target_temperature": 20.0}, New: map[string]any{"target_temperature": 22.0}},
+		{Time: start.Add(time.Hour), Principal: "automation", Endpoint: "POST /force_heating", New: map[string]any{"minutes": 30.0}},
+		{Time: start.Add(2 * time.Hour), Principal: "alice", Endpoint: "POST /set", Error: "bus error"},
+	}
+	for _, entry := range entries {
+		if err := l.Append(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all := Filter{From: start, To: start.Add(24 * time.Hour)}
+	found, err := l.Query(all)
+	if err != nil || len(found) != 3 {
+		t.Fatalf("expected 3 entries, got %d %v", len(found), err)
+	}
+	if found[0].Old["target_temperature"] != 20.0 || found[0].New["target_temperature"] != 22.0 {
+		t.Errorf("unexpected values %+v", found[0])
+	}
+
+	found, _ = l.Query(Filter{From: start, To: start.Add(24 * time.Hour), Principal: "alice"})
+	if len(found) != 2 || found[1].Error != "bus error" {
+		t.Errorf("expected entries of alice, got %+v", found)
+	}
+	found, _ = l.Query(Filter{From: start.Add(30 * time.Minute), To: start.Add(90 * time.Minute)})
+	if len(found) != 1 || found[0].Principal != "automation" {
+		t.Errorf("expected entry in time range, got %+v", found)
+	}
+
+	var disabled *Log
+	if err := disabled.Append(entries[0]); err != nil {
+		t.Errorf("disabled log should ignore entries, got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+// Decoders for Xiaomi LYWSD03MMC custom firmware advertisements (ATC1441 and PVVX)
+// sent as service data on Environmental Sensing service 0x181A.
+package bluetooththermostat
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const EnvironmentalSensingService = 0x181A
+
+const ATC_PAYLOAD_LENGTH = 13
+const PVVX_PAYLOAD_LENGTH = 15
+
+// decodeCustom decodes ATC1441 or PVVX payload, format is detected by length
+func decodeCustom(data []byte) (Reading, error) {
+	switch len(data) {
+	case ATC_PAYLOAD_LENGTH:
+		return decodeATC(data), nil
+	case PVVX_PAYLOAD_LENGTH:
+		return decodePVVX(data), nil
+	}
+	return Reading{}, fmt.Errorf("unsupported 0x181A payload length %d", len(data))
+}
+
+// decodeATC decodes ATC1441 format, big endian:
+// mac[6], temperature int16 (0.1C), humidity uint8 (%), battery uint8 (%), battery uint16 (mV), counter uint8
+func decodeATC(data []byte) Reading {
+	return Reading{
+		Temp:     float64(int16(binary.BigEndian.Uint16(data[6:8]))) / 10,
+		Humidity: float64(data[8]),
+		Battery:  float64(data[9]),
+	}
+}
+
+// decodePVVX decodes PVVX custom format, little endian:
+// mac[6] reversed, temperature int16 (0.01C), humidity uint16 (0.01%), battery uint16 (mV), battery uint8 (%), counter uint8, flags uint8
+func decodePVVX(data []byte) Reading {
+	return Reading{
+		Temp:     float64(int16(binary.LittleEndian.Uint16(data[6:8]))) / 100,
+		Humidity: float64(binary.LittleEndian.Uint16(data[8:10])) / 100,
+		Battery:  float64(data[12]),
+	}
+}
@@ -0,0 +1,45 @@
+package bluetooththermostat
+
+import "testing"
+
+func TestDecodeATC(t *testing.T) {
+	// 21.5C, 45%, 87%, 2950mV
+	data := []byte{0xA4, 0xC1, 0x38, 0x5C, 0x18, 0xA5, 0x00, 0xD7, 0x2D, 0x57, 0x0B, 0x86, 0x01}
+	reading, err := decodeCustom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reading.Temp != 21.5 || reading.Humidity != 45 || reading.Battery != 87 {
+		t.Errorf("Unexpected ATC reading %+v", reading)
+	}
+}
+
+func TestDecodeATCNegativeTemperature(t *testing.T) {
+	// -3.2C
+	data := []byte{0xA4, 0xC1, 0x38, 0xD1, 0x64, 0x5F, 0xFF, 0xE0, 0x50, 0x64, 0x0B, 0xB8, 0x02}
+	reading, err := decodeCustom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reading.Temp != -3.2 {
+		t.Errorf("Expected -3.2C, got %f", reading.Temp)
+	}
+}
+
+func TestDecodePVVX(t *testing.T) {
+	// 21.37C, 45.12%, 2950mV, 87%
+	data := []byte{0xA5, 0x18, 0x5C, 0x38, 0xC1, 0xA4, 0x59, 0x08, 0xA0, 0x11, 0x86, 0x0B, 0x57, 0x01, 0x04}
+	reading, err := decodeCustom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reading.Temp != 21.37 || reading.Humidity != 45.12 || reading.Battery != 87 {
+		t.Errorf("Unexpected PVVX reading %+v", reading)
+	}
+}
+
+func TestDecodeUnsupportedLength(t *testing.T) {
+	if _, err := decodeCustom([]byte{0x01, 0x02}); err == nil {
+		t.Error("Expected error for unsupported payload")
+	}
+}
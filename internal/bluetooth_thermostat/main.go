@@ -2,8 +2,15 @@ package bluetooththermostat
 
 import (
 	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/hoegaarden/go-bthome"
+	"github.com/ksimuk/ebus-climate/internal/config"
 	"github.com/rs/zerolog/log"
 	"tinygo.org/x/bluetooth"
 )
@@ -11,86 +18,240 @@ import (
 const BleServiceTemperature = 0xFFE0
 
 var bthomeUUID = bluetooth.New16BitUUID(binary.LittleEndian.Uint16(bthome.BTHomeUUID[:]))
-var parser *bthome.Parser
+var customUUID = bluetooth.New16BitUUID(EnvironmentalSensingService)
 
+// Reading is a measurement received from sensor, humidity and battery are -1 when unknown
+type Reading struct {
+	Temp     float64
+	Humidity float64
+	Battery  float64
+}
+
+// BluetoothThermostat is a registered sensor
 type BluetoothThermostat struct {
-	address     string
-	isConnected bool
-	// Add fields as necessary
+	address  string
+	name     string
+	role     string
+	offset   float64
+	callback func(Reading)
+
+	mu       sync.Mutex
+	last     Reading
+	lastSeen time.Time
 }
 
-func getParser() *bthome.Parser {
-	if parser == nil {
-		parser = bthome.NewParser()
-	}
-	return parser
+// Scanner runs single BLE scan and dispatches advertisements to registered sensors by address
+type Scanner struct {
+	adapter *bluetooth.Adapter
+	parser  *bthome.Parser
+
+	mu      sync.Mutex
+	sensors map[string]*BluetoothThermostat
+	running bool
+	err     error
 }
 
-func New(address string, callback func(temp, humidity, battery float64)) *BluetoothThermostat {
-	thermostat := &BluetoothThermostat{
-		isConnected: false,
-		address:     address,
+func NewScanner() *Scanner {
+	return &Scanner{
+		adapter: bluetooth.DefaultAdapter,
+		parser:  bthome.NewParser(),
+		sensors: map[string]*BluetoothThermostat{},
 	}
+}
+
+func normalizeAddress(address string) string {
+	return strings.ToUpper(strings.TrimSpace(address))
+}
 
-	getParser().AddEncryptionKey(address, "key") // TODO: load key from config
+// Register adds sensor to the registry, callback is invoked on every temperature reading
+func (s *Scanner) Register(sensor config.Sensor, callback func(Reading)) (*BluetoothThermostat, error) {
+	address := normalizeAddress(sensor.MAC)
+	if address == "" {
+		return nil, errors.New("sensor address is empty")
+	}
+	if sensor.BindKey != "" {
+		key, err := hex.DecodeString(sensor.BindKey)
+		if err != nil || len(key) != 16 {
+			return nil, fmt.Errorf("invalid bindkey for sensor %s, expected 32 hex characters", address)
+		}
+	}
 
-	go start(thermostat.onScan)
-	return thermostat
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sensors[address]; ok {
+		return nil, fmt.Errorf("sensor %s already registered", address)
+	}
+	if sensor.BindKey != "" {
+		s.parser.AddEncryptionKey(address, sensor.BindKey)
+	}
+	thermostat := &BluetoothThermostat{
+		address:  address,
+		name:     sensor.Name,
+		role:     sensor.Role,
+		offset:   sensor.Offset,
+		callback: callback,
+		last:     Reading{Humidity: -1, Battery: -1},
+	}
+	s.sensors[address] = thermostat
+	log.Info().Msgf("Registered sensor %s (%s) with role %q", address, sensor.Name, sensor.Role)
+	return thermostat, nil
 }
 
-var BTCallbacks []func(*bluetooth.Adapter, bluetooth.ScanResult)
+// Unregister removes sensor from the registry
+func (s *Scanner) Unregister(address string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sensors, normalizeAddress(address))
+}
 
-func start(onScan func(*bluetooth.Adapter, bluetooth.ScanResult)) {
-	BTCallbacks = append(BTCallbacks, onScan)
+// Sensors returns registered sensors
+func (s *Scanner) Sensors() []*BluetoothThermostat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sensors := make([]*BluetoothThermostat, 0, len(s.sensors))
+	for _, sensor := range s.sensors {
+		sensors = append(sensors, sensor)
+	}
+	return sensors
+}
 
-	adapter := bluetooth.DefaultAdapter
-	// Enable adapter
-	err := adapter.Enable()
-	if err != nil {
-		log.Info().Err(err).Msg("Failed to enable BLE adapter")
+// Start enables adapter and starts scanning in background
+func (s *Scanner) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return nil
+	}
+	if err := s.adapter.Enable(); err != nil {
+		s.err = fmt.Errorf("failed to enable BLE adapter: %w", err)
+		return s.err
 	}
+	s.running = true
+	s.err = nil
 
-	// Start scanning and define callback for scan results
-	err = adapter.Scan(func(adapter *bluetooth.Adapter, device bluetooth.ScanResult) {
-		for _, callback := range BTCallbacks {
-			callback(adapter, device)
+	go func() {
+		log.Debug().Msg("Start BLE scanning")
+		err := s.adapter.Scan(s.onScan)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.running = false
+		if err != nil {
+			s.err = fmt.Errorf("BLE scan failed: %w", err)
+			log.Error().Err(err).Msg("BLE scan stopped")
 		}
-	})
+	}()
+	return nil
+}
 
-	if err != nil && err.Error() != "Operation already in progress" {
-		log.Info().Err(err).Msg("Failed to register scan callback")
+// Stop stops scanning
+func (s *Scanner) Stop() error {
+	s.mu.Lock()
+	running := s.running
+	s.mu.Unlock()
+	if !running {
+		return nil
 	}
+	return s.adapter.StopScan()
 }
 
-func (b *BluetoothThermostat) IsConnected() bool {
-	return b.isConnected
+// Err returns last adapter error
+func (s *Scanner) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
 }
 
-func (b *BluetoothThermostat) onScan(adapter *bluetooth.Adapter, device bluetooth.ScanResult) {
-	// log.Debug().Msgf("found device: %s, RSSI: %d, Name: %s, Payload: %v", device.Address.String(), device.RSSI, device.LocalName(), device.AdvertisementPayload)
+func (s *Scanner) onScan(adapter *bluetooth.Adapter, device bluetooth.ScanResult) {
+	addr := normalizeAddress(device.Address.String())
+	s.mu.Lock()
+	sensor, ok := s.sensors[addr]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	log.Trace().Msgf("Advertisement from %s, RSSI: %d, Name: %s", addr, device.RSSI, device.LocalName())
 
-	if device.ServiceData() != nil {
-		if device.ServiceData()[0].UUID == bthomeUUID {
-			log.Debug().Msgf("Found BTHome device: %s, RSSI: %d, Name: %s", device.Address.String(), device.RSSI, device.LocalName())
-			if !device.HasServiceUUID(bthomeUUID) {
-				return
+	for _, sd := range device.ServiceData() {
+		switch sd.UUID {
+		case bthomeUUID:
+			packets, err := s.parser.Parse(addr, nil, sd.Data)
+			if err != nil {
+				log.Debug().Err(err).Msgf("[%s] failed to parse BTHome data", addr)
+				continue
 			}
-
-			addr := device.Address.String()
-
-			for _, sd := range device.ServiceData() {
-				packets, err := parser.Parse(addr, nil, sd.Data)
-				if err != nil {
-					log.Printf("[%s] error: %v\n", addr, err)
-					continue
-				}
-				for _, p := range packets {
-					log.Printf("[%s] %s\n", addr, p)
-				}
+			sensor.onPackets(packets)
+		case customUUID:
+			reading, err := decodeCustom(sd.Data)
+			if err != nil {
+				log.Debug().Err(err).Msgf("[%s] failed to parse ATC/PVVX data", addr)
+				continue
 			}
+			sensor.onReading(reading)
+		}
+	}
+}
+
+func (b *BluetoothThermostat) Address() string {
+	return b.address
+}
+
+func (b *BluetoothThermostat) Name() string {
+	return b.name
+}
+
+func (b *BluetoothThermostat) Role() string {
+	return b.role
+}
+
+// Last returns the last reading and the time it was received
+func (b *BluetoothThermostat) Last() (Reading, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.last, b.lastSeen
+}
+
+// LastSeen returns time of the last received measurement
+func (b *BluetoothThermostat) LastSeen() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastSeen
+}
+
+// onPackets collects measurements from BTHome packets and reports them if temperature was received
+func (b *BluetoothThermostat) onPackets(packets []bthome.Packet) {
+	b.mu.Lock()
+	reading := Reading{Humidity: b.last.Humidity, Battery: b.last.Battery}
+	b.mu.Unlock()
+
+	hasTemp := false
+	for _, p := range packets {
+		log.Trace().Msgf("[%s] %s", b.address, p)
+		switch v := p.(type) {
+		case bthome.Temperature:
+			reading.Temp = float64(v)
+			hasTemp = true
+		case bthome.Humidity:
+			reading.Humidity = float64(v)
+		case bthome.Battery:
+			reading.Battery = float64(v)
 		}
 	}
-	// if device.Address.String() == b.address {
-	// 	log.Info().Msgf("Found target device: %s", device.Address.String())
-	// }
+	if hasTemp {
+		b.onReading(reading)
+	}
+}
+
+// onReading applies calibration and reports the reading
+func (b *BluetoothThermostat) onReading(reading Reading) {
+	reading.Temp += b.offset
+
+	b.mu.Lock()
+	b.last = reading
+	b.lastSeen = time.Now()
+	b.mu.Unlock()
+
+	log.Debug().Msgf("[%s] temperature %.2f, humidity %.1f, battery %.0f", b.address, reading.Temp, reading.Humidity, reading.Battery)
+	if b.callback != nil {
+		b.callback(reading)
+	}
 }
@@ -1,9 +1,17 @@
 package climate
 
+import (
+	"time"
+)
+
 type Climate interface {
 	Info() ([]string, error)
-	SetInsideOverride(temp float64)
-	SetOutsideOverride(temp float64)
+	SetInsideOverride(temp float64, ttl time.Duration)  // ttl 0 uses configured default
+	SetOutsideOverride(temp float64, ttl time.Duration) // ttl 0 uses configured default
+	SetInsideTemp(source string, temp float64)
+	SetOutsideTemp(source string, temp float64)
+	SetWindSpeed(source string, speed float64)       // m/s
+	SetIrradiance(source string, irradiance float64) // W/m2
 	GetInsideTemp() float64
 	GetOutsideTemp() float64
 	GetMode() string
@@ -33,7 +41,18 @@ type Climate interface {
 	GetHeatLossBalance() float64
 	Shutdown()
 	GetStat() Stat
+	GetBusStats() BusStats
+	GetCycles(from, to time.Time) ([]Cycle, error)
 	RunFor(minutes int)
+	Events() *EventBus
+	Health() Health
+}
+
+// BusStats are counters of requests to boiler bus
+type BusStats struct {
+	Requests       uint64  // total requests
+	Errors         uint64  // failed requests
+	LatencySeconds float64 // total time spent in requests
 }
 
 type BoilerInfo struct {
@@ -0,0 +1,32 @@
+package climate
+
+import "time"
+
+// cycle triggers
+const TRIGGER_MODEL = "model"       // heat loss model
+const TRIGGER_MANUAL = "manual"     // /force_heating
+const TRIGGER_OVERRIDE = "override" // /override?force_heating=1
+
+// Cycle is a single boiler burn
+type Cycle struct {
+	Start            time.Time `json:"start"`
+	End              time.Time `json:"end"`
+	Trigger          string    `json:"trigger"`           // model, manual, override
+	PlannedMinutes   float64   `json:"planned_minutes"`   // including extensions by further requests
+	ActualMinutes    float64   `json:"actual_minutes"`    // relay on time
+	Extensions       int       `json:"extensions"`        // number of extensions by further requests
+	HwcExtensions    int       `json:"hwc_extensions"`    // minutes extended because of hot water demand
+	InsideTempStart  float64   `json:"inside_temp_start"` // C
+	InsideTempEnd    float64   `json:"inside_temp_end"`
+	OutsideTempStart float64   `json:"outside_temp_start"`
+	OutsideTempEnd   float64   `json:"outside_temp_end"`
+	TargetTemp       float64   `json:"target_temp"`
+	HeatLossBalance  float64   `json:"heat_loss_balance"` // balance when cycle started in Wh
+	EnergyEstimated  float64   `json:"energy_estimated"`  // power x burn time in kWh
+	EnergyBoiler     float64   `json:"energy_boiler"`     // boiler heating counter delta in kWh, -1 if unknown
+}
+
+type CycleLog interface {
+	Append(cycle Cycle) error
+	Query(from, to time.Time) ([]Cycle, error)
+}
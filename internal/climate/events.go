@@ -0,0 +1,63 @@
+package climate
+
+import (
+	"sync"
+	"time"
+)
+
+const EVENT_BOILER = "boiler"                   // values read from boiler changed
+const EVENT_LOSS = "loss"                       // heat loss balance recalculated
+const EVENT_HEATING_STARTED = "heating_started" // boiler fired
+const EVENT_HEATING_STOPPED = "heating_stopped" // boiler stopped
+const EVENT_SETTINGS = "settings"               // mode or target changed
+
+const EVENT_BUFFER = 32 // events buffered per subscriber, slow subscribers lose events
+
+// Event is a state diff, Changes holds only changed fields
+type Event struct {
+	Type    string         `json:"type"`
+	Time    time.Time      `json:"time"`
+	Changes map[string]any `json:"changes"`
+}
+
+// EventBus fans out events to subscribers, nil bus drops events
+type EventBus struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: map[chan Event]struct{}{}}
+}
+
+// Subscribe returns channel of events and function to unsubscribe
+func (b *EventBus) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, EVENT_BUFFER)
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish sends event to all subscribers without blocking
+func (b *EventBus) Publish(eventType string, changes map[string]any) {
+	if b == nil || len(changes) == 0 {
+		return
+	}
+	event := Event{Type: eventType, Time: time.Now(), Changes: changes}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package climate
+
+import "testing"
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+	events, unsubscribe := bus.Subscribe()
+
+	bus.Publish(EVENT_SETTINGS, map[string]any{"mode": "heating"})
+	bus.Publish(EVENT_BOILER, map[string]any{}) // empty diff is not published
+	event := <-events
+	if event.Type != EVENT_SETTINGS || event.Changes["mode"] != "heating" {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	// slow subscriber does not block publisher
+	for i := 0; i < EVENT_BUFFER*2; i++ {
+		bus.Publish(EVENT_LOSS, map[string]any{"heat_loss_balance": i})
+	}
+	if len(events) != EVENT_BUFFER {
+		t.Errorf("expected %d buffered events, got %d", EVENT_BUFFER, len(events))
+	}
+
+	unsubscribe()
+	unsubscribe()
+	var nilBus *EventBus
+	nilBus.Publish(EVENT_LOSS, map[string]any{"heat_loss_balance": 1})
+}
@@ -0,0 +1,49 @@
+package climate
+
+const HEALTH_OK = "ok"
+const HEALTH_DEGRADED = "degraded" // works with reduced accuracy
+const HEALTH_FAILED = "failed"     // controller can't safely operate
+
+const COMPONENT_EBUSD = "ebusd"
+const COMPONENT_INSIDE_SENSOR = "inside_sensor"
+const COMPONENT_OUTSIDE_SENSOR = "outside_sensor"
+const COMPONENT_STATE_STORE = "state_store"
+const COMPONENT_RELAY = "relay"
+const COMPONENT_CYCLER = "cycler"
+
+// ComponentHealth is status of one subsystem
+type ComponentHealth struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	Message    string  `json:"message,omitempty"`
+	Updated    string  `json:"updated,omitempty"`     // last successful activity in RFC3339 format
+	AgeSeconds float64 `json:"age_seconds,omitempty"` // seconds since last successful activity
+}
+
+type Health struct {
+	Components []ComponentHealth `json:"components"`
+}
+
+// Status returns worst status of all components
+func (h Health) Status() string {
+	status := HEALTH_OK
+	for _, c := range h.Components {
+		switch c.Status {
+		case HEALTH_FAILED:
+			return HEALTH_FAILED
+		case HEALTH_DEGRADED:
+			status = HEALTH_DEGRADED
+		}
+	}
+	return status
+}
+
+// Component returns health of named component
+func (h Health) Component(name string) (ComponentHealth, bool) {
+	for _, c := range h.Components {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return ComponentHealth{}, false
+}
@@ -0,0 +1,167 @@
+package climate
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reading sources, ordered by default priority
+const SOURCE_OVERRIDE = "override" // manual override
+const SOURCE_BLE = "ble"           // BLE sensor
+const SOURCE_HTTP = "http"         // pushed via HTTP API
+const SOURCE_EBUS = "ebus"         // boiler sensor
+const SOURCE_FORECAST = "forecast" // weather forecast
+
+// SensorSource returns source of a single sensor of kind (e.g. ble:<mac>),
+// readings of sensors are kept apart and use settings of their kind
+func SensorSource(kind string, id string) string {
+	return kind + ":" + id
+}
+
+// SourceSettings of a source, zero fields keep default of the source
+type SourceSettings struct {
+	Priority int           // higher wins
+	TTL      time.Duration // reading expires after
+}
+
+var DefaultSources = map[string]SourceSettings{
+	SOURCE_OVERRIDE: {Priority: 100, TTL: 2 * time.Hour},
+	SOURCE_BLE:      {Priority: 50, TTL: 15 * time.Minute},
+	SOURCE_HTTP:     {Priority: 40, TTL: 30 * time.Minute},
+	SOURCE_EBUS:     {Priority: 30, TTL: 15 * time.Minute},
+	SOURCE_FORECAST: {Priority: 10, TTL: 3 * time.Hour},
+}
+
+type SourceStatus struct {
+	Source   string  `json:"source"`
+	Value    float64 `json:"value"`
+	Priority int     `json:"priority"`
+	Updated  string  `json:"updated"` // RFC3339
+	Expires  string  `json:"expires"` // RFC3339
+	Expired  bool    `json:"expired"`
+	Active   bool    `json:"active"` // value used by controller
+}
+
+type sourceReading struct {
+	value   float64
+	updated time.Time
+	ttl     time.Duration
+}
+
+// Input merges readings of one measured value (temperature, wind speed, irradiance)
+// from several sources, the highest priority reading which has not expired wins.
+type Input struct {
+	mu       sync.Mutex
+	settings map[string]SourceSettings
+	readings map[string]*sourceReading
+	active   string
+}
+
+func NewInput(settings map[string]SourceSettings) *Input {
+	merged := map[string]SourceSettings{}
+	for source, s := range DefaultSources {
+		merged[source] = s
+	}
+	for source, s := range settings {
+		m := merged[source]
+		if s.Priority != 0 {
+			m.Priority = s.Priority
+		}
+		if s.TTL != 0 {
+			m.TTL = s.TTL
+		}
+		merged[source] = m
+	}
+	return &Input{
+		settings: merged,
+		readings: map[string]*sourceReading{},
+	}
+}
+
+// Update records reading from source, ttl 0 uses source default
+func (in *Input) Update(source string, value float64, ttl time.Duration, now time.Time) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if ttl <= 0 {
+		ttl = in.setting(source).TTL
+	}
+	in.readings[source] = &sourceReading{value: value, updated: now, ttl: ttl}
+}
+
+// setting returns settings of source, sensor sources use settings of their kind
+func (in *Input) setting(source string) SourceSettings {
+	kind, _, _ := strings.Cut(source, ":")
+	return in.settings[kind]
+}
+
+// better reports if source a wins over b, of equal priority the active source is kept
+// so the controller does not jump between sensors on every reading
+func (in *Input) better(a string, b string) bool {
+	pa, pb := in.setting(a).Priority, in.setting(b).Priority
+	if pa != pb {
+		return pa > pb
+	}
+	if a == in.active || b == in.active {
+		return a == in.active
+	}
+	return a < b
+}
+
+// Clear removes reading of source
+func (in *Input) Clear(source string) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	delete(in.readings, source)
+}
+
+func (r *sourceReading) expired(now time.Time) bool {
+	return r.ttl > 0 && now.Sub(r.updated) > r.ttl
+}
+
+// Current returns value of the highest priority fresh source
+func (in *Input) Current(now time.Time) (value float64, source string, updated time.Time, ok bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	best := ""
+	for s, r := range in.readings {
+		if r.expired(now) {
+			continue
+		}
+		if best == "" || in.better(s, best) {
+			best = s
+		}
+	}
+	in.active = best
+	if best == "" {
+		return 0, "", time.Time{}, false
+	}
+	r := in.readings[best]
+	return r.value, best, r.updated, true
+}
+
+// Status returns state of all known sources ordered by priority
+func (in *Input) Status(now time.Time) []SourceStatus {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	status := []SourceStatus{}
+	for s, r := range in.readings {
+		status = append(status, SourceStatus{
+			Source:   s,
+			Value:    r.value,
+			Priority: in.setting(s).Priority,
+			Updated:  r.updated.Format(time.RFC3339),
+			Expires:  r.updated.Add(r.ttl).Format(time.RFC3339),
+			Expired:  r.expired(now),
+			Active:   s == in.active,
+		})
+	}
+	sort.Slice(status, func(i, j int) bool {
+		if status[i].Priority != status[j].Priority {
+			return status[i].Priority > status[j].Priority
+		}
+		return status[i].Source < status[j].Source
+	})
+	return status
+}
@@ -0,0 +1,98 @@
+package climate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInputPriority(t *testing.T) {
+	input := NewInput(nil)
+	now := time.Now()
+
+	input.Update(SOURCE_BLE, 20.5, 0, now)
+	input.Update(SOURCE_FORECAST, 3, 0, now)
+	value, source, _, ok := input.Current(now)
+	if !ok || source != SOURCE_BLE || value != 20.5 {
+		t.Errorf("Expected BLE reading, got %s %f", source, value)
+	}
+
+	input.Update(SOURCE_OVERRIDE, 18, time.Hour, now)
+	value, source, _, _ = input.Current(now)
+	if source != SOURCE_OVERRIDE || value != 18 {
+		t.Errorf("Expected override to win, got %s %f", source, value)
+	}
+
+	// override expires back to live reading
+	input.Update(SOURCE_BLE, 21, 0, now.Add(61*time.Minute))
+	value, source, _, _ = input.Current(now.Add(61 * time.Minute))
+	if source != SOURCE_BLE || value != 21 {
+		t.Errorf("Expected expired override to fall back to BLE, got %s %f", source, value)
+	}
+}
+
+func TestInputDemotesDeadSensor(t *testing.T) {
+	input := NewInput(map[string]SourceSettings{
+		SOURCE_BLE: {Priority: 50, TTL: 10 * time.Minute},
+	})
+	now := time.Now()
+	input.Update(SOURCE_BLE, 20, 0, now)
+	input.Update(SOURCE_EBUS, 5, 0, now.Add(14*time.Minute))
+
+	_, source, _, ok := input.Current(now.Add(15 * time.Minute))
+	if !ok || source != SOURCE_EBUS {
+		t.Errorf("Expected stale BLE sensor to be demoted, got %s", source)
+	}
+
+	if _, _, _, ok := input.Current(now.Add(time.Hour)); ok {
+		t.Error("Expected no fresh reading")
+	}
+}
+
+func TestInputPartialSettings(t *testing.T) {
+	input := NewInput(map[string]SourceSettings{
+		SOURCE_BLE: {TTL: 10 * time.Minute},
+	})
+	if s := input.settings[SOURCE_BLE]; s.Priority != DefaultSources[SOURCE_BLE].Priority || s.TTL != 10*time.Minute {
+		t.Errorf("Expected configured TTL with default priority, got %+v", s)
+	}
+
+	// BLE still wins over forecast
+	now := time.Now()
+	input.Update(SOURCE_BLE, 20, 0, now)
+	input.Update(SOURCE_FORECAST, 3, 0, now)
+	if _, source, _, _ := input.Current(now); source != SOURCE_BLE {
+		t.Errorf("Expected BLE reading, got %s", source)
+	}
+	if _, source, _, _ := input.Current(now.Add(11 * time.Minute)); source != SOURCE_FORECAST {
+		t.Errorf("Expected configured TTL to expire BLE reading, got %s", source)
+	}
+}
+
+func TestInputSensorsOfSameKind(t *testing.T) {
+	input := NewInput(nil)
+	kitchen := SensorSource(SOURCE_BLE, "A4:C1:38:00:00:01")
+	bedroom := SensorSource(SOURCE_BLE, "A4:C1:38:00:00:02")
+	now := time.Now()
+
+	input.Update(kitchen, 20, 0, now)
+	input.Update(SOURCE_FORECAST, 3, 0, now)
+	if _, source, _, _ := input.Current(now); source != kitchen {
+		t.Fatalf("Expected kitchen sensor, got %s", source)
+	}
+
+	// reading of another sensor does not switch active one
+	input.Update(bedroom, 18, 0, now.Add(time.Minute))
+	if value, source, _, _ := input.Current(now.Add(time.Minute)); source != kitchen || value != 20 {
+		t.Errorf("Expected kitchen sensor to stay active, got %s %f", source, value)
+	}
+
+	// kitchen stops reporting and expires with BLE TTL, bedroom takes over
+	input.Update(bedroom, 18.5, 0, now.Add(14*time.Minute))
+	value, source, _, _ := input.Current(now.Add(16 * time.Minute))
+	if source != bedroom || value != 18.5 {
+		t.Errorf("Expected dead kitchen sensor to be demoted, got %s %f", source, value)
+	}
+	if status := input.Status(now.Add(16 * time.Minute)); len(status) != 3 || status[0].Source != kitchen || !status[0].Expired || !status[1].Active {
+		t.Errorf("Expected sensors listed separately, got %+v", status)
+	}
+}
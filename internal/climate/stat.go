@@ -4,8 +4,33 @@ type Stat struct {
 	UsageHeating    float64 `json:"usage_heating"`     // total consumption for heating in kWh
 	UsageHotWater   float64 `json:"usage_hot_water"`   // total consumption for hot water in kWh
 	CurrentHeatLoss float64 `json:"current_heat_loss"` // current heat loss in W
+	LossBase        float64 `json:"loss_base"`         // heat loss by outside temperature in W
+	LossWind        float64 `json:"loss_wind"`         // extra heat loss by wind in W
+	LossSolar       float64 `json:"loss_solar"`        // solar gain in W, negative
 	WaterPressure   float64 `json:"water_pressure"`    // current water pressure in bar
 	Runtime         int     `json:"runtime"`           // current runtime in minutes
 	HwcDemand       string  `json:"hwc_demand"`        // hot water demand status
 	HeatingEndTime  string  `json:"heating_end_time"`  // heating cycle end time in RFC3339 format
+	Cycles          int     `json:"cycles"`            // number of burns since start
+	BurnSeconds     float64 `json:"burn_seconds"`      // total burn time since start
+	PreheatStart    string  `json:"preheat_start"`     // predicted optimum start time in RFC3339 format
+	SetbackStart    string  `json:"setback_start"`     // predicted optimum stop time in RFC3339 format
+	WarmupRate      float64 `json:"warmup_rate"`       // warm-up rate in C per hour at full power
+	HwMode          string  `json:"hw_mode"`           // hot water mode: eco, comfort, off, legionella
+	HwStorageTemp   float64 `json:"hw_storage_temp"`   // cylinder temperature read from boiler
+	LegionellaLast  string  `json:"legionella_last"`   // last confirmed anti-legionella boost in RFC3339 format
+
+	InsideTempUpdated  string         `json:"inside_temp_updated"`  // last inside temperature reading in RFC3339 format
+	OutsideTempUpdated string         `json:"outside_temp_updated"` // last outside temperature reading in RFC3339 format
+	InsideTempSource   string         `json:"inside_temp_source"`   // source used for inside temperature
+	OutsideTempSource  string         `json:"outside_temp_source"`  // source used for outside temperature
+	InsideSources      []SourceStatus `json:"inside_sources"`       // all inside temperature sources
+	OutsideSources     []SourceStatus `json:"outside_sources"`      // all outside temperature sources
+	BoilerUpdated      string         `json:"boiler_updated"`       // last successful boiler read in RFC3339 format
+	DegradedMode       string         `json:"degraded_mode"`        // empty, outside_only, inside_only or frost_protection when inputs are stale
+	ForecastTemp       float64        `json:"forecast_temp"`        // average forecast temperature over lookahead
+
+	KWhPerDegreeDay      float64 `json:"kwh_per_degree_day"`    // heating efficiency of last finished day
+	EfficiencyBaseline   float64 `json:"efficiency_baseline"`   // kWh per degree-day of preceding days
+	EfficiencyRegression bool    `json:"efficiency_regression"` // last finished day was less efficient than baseline
 }
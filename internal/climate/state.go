@@ -22,6 +22,12 @@ type ClimateState struct {
 	ConsumptionHeating float64 `json:"consumption_heating"` // total consumption for heating in kWh
 
 	HeatLoss float64 `json:"heat_loss"` // current heat loss balance
+
+	ScheduleApplied string  `json:"schedule_applied"` // start time of the last applied schedule point
+	WarmupRate      float64 `json:"warmup_rate"`      // learned warm-up rate in C per hour at full power
+
+	HWScheduleApplied string `json:"hw_schedule_applied"` // start time of the last applied hot water schedule point
+	LegionellaLast    string `json:"legionella_last"`     // last confirmed anti-legionella boost
 }
 
 type ClimateStateStore interface {
@@ -30,11 +36,18 @@ type ClimateStateStore interface {
 	SaveNow(state *ClimateState) error
 }
 
+// StoreStatus is implemented by stores which report result of last write
+type StoreStatus interface {
+	LastSave() (time.Time, error)
+}
+
 type FileClimateStore struct {
 	filePath     string
 	mu           sync.Mutex
 	pendingState *ClimateState
 	timer        *time.Timer
+	lastSave     time.Time // last successful write
+	lastErr      error     // error of last write, nil if it succeeded
 }
 
 const saveDelay = 120 * time.Second
@@ -74,7 +87,9 @@ func (s *FileClimateStore) Save(state *ClimateState) error {
 
 	s.timer = time.AfterFunc(saveDelay, func() {
 		if s.pendingState != nil {
-			s.SaveNow(s.pendingState)
+			if err := s.SaveNow(s.pendingState); err != nil {
+				log.Error().Err(err).Msg("Failed to save climate state")
+			}
 			s.pendingState = nil
 		}
 		s.timer.Stop()
@@ -89,14 +104,30 @@ func (s *FileClimateStore) SaveNow(state *ClimateState) error {
 	defer s.mu.Unlock()
 
 	log.Debug().Msg("Saving climate state to file")
+	s.lastErr = s.write(state)
+	if s.lastErr == nil {
+		s.lastSave = time.Now()
+	}
+	return s.lastErr
+}
+
+func (s *FileClimateStore) write(state *ClimateState) error {
 	file, err := os.Create(s.filePath)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
 	encoder := json.NewEncoder(file)
 	encoder.SetIndent("", "  ")
-	err = encoder.Encode(state)
-	return err
+	if err := encoder.Encode(state); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LastSave returns time of last successful write and error of last write
+func (s *FileClimateStore) LastSave() (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSave, s.lastErr
 }
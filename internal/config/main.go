@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v3"
@@ -13,9 +14,84 @@ type Config struct {
 		Host    string `yaml:"host"`
 		Port    string `yaml:"port"`
 		Circuit string `yaml:"circuit"`
+
+		OutsideTempMessage string `yaml:"outside_temp_message"` // e.g. DisplayedOutsideTemp, disabled if empty
+		OutsideTempCircuit string `yaml:"outside_temp_circuit"` // circuit of outside temperature, defaults to circuit
+		ErrorMessage       string `yaml:"error_message"`        // current boiler error, e.g. currenterror, disabled if empty
 	} `yaml:"ebus"`
 
-	WebPort int `yaml:"web_port"`
+	Sensors []Sensor `yaml:"sensors"` // BLE temperature sensors
+
+	// temperature source priorities and TTLs, keys: override, ble, http, ebus, forecast
+	TemperatureSources map[string]TemperatureSource `yaml:"temperature_sources"`
+
+	Forecast struct {
+		Provider  string  `yaml:"provider"` // open-meteo, met-no or file, disabled if empty
+		Latitude  float64 `yaml:"latitude"`
+		Longitude float64 `yaml:"longitude"`
+		URL       string  `yaml:"url"`       // override provider API URL
+		File      string  `yaml:"file"`      // forecast JSON for file provider
+		Refresh   float64 `yaml:"refresh"`   // minutes between forecast updates
+		Lookahead float64 `yaml:"lookahead"` // hours of forecast used to plan cycles, 0 disables
+	} `yaml:"forecast"`
+
+	History struct {
+		Path         string  `yaml:"path"`          // directory for history files, disabled if empty
+		RawRetention int     `yaml:"raw_retention"` // days of per minute records
+		Retention    int     `yaml:"retention"`     // days of downsampled records
+		Downsample   float64 `yaml:"downsample"`    // minutes per downsampled record
+	} `yaml:"history"`
+
+	CycleLog string `yaml:"cycle_log"` // file with heating cycle events, disabled if empty
+	AuditLog string `yaml:"audit_log"` // append-only log of control actions, disabled if empty
+
+	Energy struct {
+		File           string       `yaml:"file"`            // energy ledger, disabled if empty
+		Calibrate      bool         `yaml:"calibrate"`       // align counter factor of energy reports to nominal power x burn time
+		StandingCharge float64      `yaml:"standing_charge"` // gas standing charge per day
+		UnitPrice      float64      `yaml:"unit_price"`      // gas price per kWh
+		TimeOfUse      []TariffRate `yaml:"time_of_use"`     // unit price from time, until next rate
+	} `yaml:"energy"`
+
+	DegreeDays struct {
+		Base                float64 `yaml:"base"`                 // base temperature of heating degree-days
+		BaselineDays        int     `yaml:"baseline_days"`        // days before compared against for efficiency trend
+		MinDegreeDays       float64 `yaml:"min_degree_days"`      // days with fewer degree-days are excluded from trends
+		RegressionThreshold float64 `yaml:"regression_threshold"` // relative kWh per degree-day increase flagged as regression
+	} `yaml:"degree_days"`
+
+	MQTT struct {
+		Broker          string  `yaml:"broker"` // e.g. tcp://localhost:1883, disabled if empty
+		Username        string  `yaml:"username"`
+		Password        string  `yaml:"password"`
+		ClientID        string  `yaml:"client_id"`
+		TopicPrefix     string  `yaml:"topic_prefix"`     // state and command topics
+		DiscoveryPrefix string  `yaml:"discovery_prefix"` // Home Assistant discovery prefix
+		Interval        float64 `yaml:"interval"`         // seconds between state checks
+	} `yaml:"mqtt"`
+
+	Alerts struct {
+		Interval float64     `yaml:"interval"` // seconds between rule evaluations
+		Repeat   float64     `yaml:"repeat"`   // minutes between repeated notifications of firing alert, 0 notifies once
+		Rules    []AlertRule `yaml:"rules"`
+		Sinks    []AlertSink `yaml:"sinks"`
+	} `yaml:"alerts"`
+
+	WebPort int    `yaml:"web_port"`
+	Listen  string `yaml:"listen"` // bind address, e.g. 127.0.0.1 or unix:/run/ebus-climate.sock, all interfaces if empty
+	TLS     struct {
+		Cert              string `yaml:"cert"`                // PEM certificate, HTTPS disabled if empty, reloaded on change
+		Key               string `yaml:"key"`                 // PEM private key
+		ClientCA          string `yaml:"client_ca"`           // CA for client certificates (mTLS)
+		RequireClientCert bool   `yaml:"require_client_cert"` // reject connections without valid client certificate
+	} `yaml:"tls"`
+	Limits struct {
+		MinTargetTemp   float64 `yaml:"min_target_temp"`
+		MaxTargetTemp   float64 `yaml:"max_target_temp"`
+		MinHWTargetTemp int     `yaml:"min_hw_target_temp"` // 0 is always accepted and disables hot water target
+		MaxHWTargetTemp int     `yaml:"max_hw_target_temp"`
+	} `yaml:"limits"` // accepted ranges of targets set through API and MQTT
+	Auth    Auth `yaml:"auth"` // disabled if no tokens, users or client certificates are configured
 	Climate struct {
 		Power              int     `yaml:"power"`        // boiler power in kwh
 		MinRunTime         float64 `yaml:"min_run_time"` // minimum boiler run time in minutes
@@ -26,11 +102,114 @@ type Config struct {
 		DurationMultiplier float64 `yaml:"duration_multiplier"`
 		InternalSensorMAC  string  `yaml:"internal_sensor_mac"`
 		ExternalSensorMAC  string  `yaml:"external_sensor_mac"`
+
+		Schedule     []SchedulePoint `yaml:"schedule"`      // target temperature program
+		OptimumStart bool            `yaml:"optimum_start"` // start heating early to reach scheduled target on time
+		OptimumStop  bool            `yaml:"optimum_stop"`  // stop heating early to coast into setback
+		WarmupRate   float64         `yaml:"warmup_rate"`   // initial warm-up rate in C per hour at full power, learned later
+		MaxPreheat   float64         `yaml:"max_preheat"`   // maximum pre-heat or coast time in minutes
+
+		FrostDutyCycle float64 `yaml:"frost_duty_cycle"` // fraction of boiler power when all temperatures are stale
+		DesignTemp     float64 `yaml:"design_temp"`      // outside temperature assumed when outside temperature is stale and there is no forecast
+
+		SolarGain float64 `yaml:"solar_gain"` // W of gain per W/m2 of irradiance, effective glazing area
+		WindLoss  float64 `yaml:"wind_loss"`  // extra fraction of heat loss per m/s of wind speed
 	}
 
+	HotWater struct {
+		EcoTemp            int                     `yaml:"eco_temp"`             // cylinder temperature for eco periods, default 45
+		ComfortTemp        int                     `yaml:"comfort_temp"`         // cylinder temperature for comfort periods, default 55
+		Schedule           []HotWaterSchedulePoint `yaml:"schedule"`             // eco/comfort/off program
+		StorageTempMessage string                  `yaml:"storage_temp_message"` // ebusd message with cylinder temperature
+		Legionella         struct {
+			Day     string  `yaml:"day"`     // weekly boost day, disabled if empty
+			Time    string  `yaml:"time"`    // HH:MM
+			Temp    int     `yaml:"temp"`    // boost temperature
+			Hold    float64 `yaml:"hold"`    // minutes at boost temperature to confirm the cycle
+			Timeout float64 `yaml:"timeout"` // minutes to give up reaching boost temperature
+		} `yaml:"legionella"`
+	} `yaml:"hot_water"`
+
 	LogLevel string `yaml:"log_level"`
 }
 
+const SENSOR_ROLE_INSIDE = "inside"
+const SENSOR_ROLE_OUTSIDE = "outside"
+
+type Sensor struct {
+	MAC     string  `yaml:"mac"`
+	BindKey string  `yaml:"bindkey"` // BTHome encryption key in hex, empty for unencrypted sensors
+	Role    string  `yaml:"role"`    // inside, outside or empty for monitoring only
+	Name    string  `yaml:"name"`
+	Offset  float64 `yaml:"offset"` // calibration offset added to temperature
+}
+
+type TemperatureSource struct {
+	Priority int     `yaml:"priority"` // higher wins, default of source if 0
+	TTL      float64 `yaml:"ttl"`      // minutes until reading expires, default of source if 0
+}
+
+type SchedulePoint struct {
+	Days []string `yaml:"days"` // mon, tue, ... or ranges like mon-fri, empty for every day
+	Time string   `yaml:"time"` // HH:MM
+	Temp float64  `yaml:"temp"`
+}
+
+const SCOPE_READ = "read"       // state, history and reports
+const SCOPE_CONTROL = "control" // read and change settings, force heating
+
+type Auth struct {
+	Tokens          []AuthToken `yaml:"tokens"`            // sent as Authorization: Bearer or ?token=
+	Users           []AuthUser  `yaml:"users"`             // HTTP basic auth
+	ClientCertScope string      `yaml:"client_cert_scope"` // scope of verified TLS client certificates, disabled if empty
+}
+
+type AuthToken struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+	Scope string `yaml:"scope"` // read or control
+}
+
+type AuthUser struct {
+	Username       string `yaml:"username"`
+	Password       string `yaml:"password"`
+	PasswordSHA256 string `yaml:"password_sha256"` // hex encoded, used instead of password
+	Scope          string `yaml:"scope"`           // read or control
+}
+
+type AlertRule struct {
+	Name      string  `yaml:"name"`      // defaults to type
+	Type      string  `yaml:"type"`      // pressure_low, comfort, ebusd_unreachable, boiler_error, battery_low, efficiency_regression
+	Threshold float64 `yaml:"threshold"` // bar, degrees below target or battery percent, rule default if 0
+	For       float64 `yaml:"for"`       // minutes condition must hold before alert fires
+	Severity  string  `yaml:"severity"`  // info, warning (default) or critical
+}
+
+type AlertSink struct {
+	Type     string   `yaml:"type"`  // webhook, ntfy, gotify, smtp or mqtt
+	URL      string   `yaml:"url"`   // webhook, ntfy topic or gotify message URL
+	Token    string   `yaml:"token"` // ntfy access token or gotify application token
+	Topic    string   `yaml:"topic"` // mqtt topic below mqtt.topic_prefix, defaults to alerts
+	Host     string   `yaml:"host"`  // smtp
+	Port     int      `yaml:"port"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+type TariffRate struct {
+	Days  []string `yaml:"days"`  // mon, tue, ... or ranges like mon-fri, empty for every day
+	Time  string   `yaml:"time"`  // HH:MM
+	Price float64  `yaml:"price"` // per kWh
+}
+
+type HotWaterSchedulePoint struct {
+	Days []string `yaml:"days"` // mon, tue, ... or ranges like mon-fri, empty for every day
+	Time string   `yaml:"time"` // HH:MM
+	Mode string   `yaml:"mode"` // eco, comfort, off
+}
+
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -41,10 +220,66 @@ func Load(path string) (*Config, error) {
 	// set defaults
 	cfg.Climate.AdjustmentRate = 3.0
 	cfg.Climate.DurationMultiplier = 1.0
+	cfg.Climate.WarmupRate = 2.0
+	cfg.Climate.MaxPreheat = 180
+	cfg.Climate.FrostDutyCycle = 0.1
+	cfg.Climate.DesignTemp = -3
+	cfg.Forecast.Refresh = 60
+	cfg.Forecast.Lookahead = 3
+	cfg.History.Path = "history"
+	cfg.CycleLog = "cycles.jsonl"
+	cfg.AuditLog = "audit.jsonl"
+	cfg.Energy.File = "energy.json"
+	cfg.Limits.MinTargetTemp = 5
+	cfg.Limits.MaxTargetTemp = 30
+	cfg.Limits.MinHWTargetTemp = 35
+	cfg.Limits.MaxHWTargetTemp = 65
+	cfg.Alerts.Interval = 60
+	cfg.MQTT.ClientID = "ebus-climate"
+	cfg.MQTT.TopicPrefix = "ebus-climate"
+	cfg.MQTT.DiscoveryPrefix = "homeassistant"
+	cfg.MQTT.Interval = 10
+	cfg.DegreeDays.Base = 15.5
+	cfg.DegreeDays.BaselineDays = 28
+	cfg.DegreeDays.MinDegreeDays = 2
+	cfg.DegreeDays.RegressionThreshold = 0.25
+	cfg.History.RawRetention = 7
+	cfg.History.Retention = 365
+	cfg.History.Downsample = 15
+	cfg.HotWater.EcoTemp = 45
+	cfg.HotWater.ComfortTemp = 55
+	cfg.HotWater.StorageTempMessage = "HwcStorageTemp"
+	cfg.HotWater.Legionella.Temp = 60
+	cfg.HotWater.Legionella.Hold = 10
+	cfg.HotWater.Legionella.Timeout = 120
 
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, err
 	}
+	cfg.addLegacySensors()
 	log.Trace().Msgf("Loaded config from %s: %+v", path, cfg)
 	return &cfg, nil
 }
+
+// addLegacySensors converts internal/external sensor MACs to sensor registry entries
+func (cfg *Config) addLegacySensors() {
+	legacy := []Sensor{
+		{MAC: cfg.Climate.InternalSensorMAC, Role: SENSOR_ROLE_INSIDE, Name: "inside"},
+		{MAC: cfg.Climate.ExternalSensorMAC, Role: SENSOR_ROLE_OUTSIDE, Name: "outside"},
+	}
+	for _, sensor := range legacy {
+		if sensor.MAC == "" {
+			continue
+		}
+		found := false
+		for _, s := range cfg.Sensors {
+			if strings.EqualFold(s.MAC, sensor.MAC) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			cfg.Sensors = append(cfg.Sensors, sensor)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Package cyclelog persists boiler cycles as JSON lines for diagnosing short-cycling.
+package cyclelog
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/climate"
+)
+
+type FileCycleLog struct {
+	filePath string
+	mu       sync.Mutex
+}
+
+// New creates log writing to filePath, nil if cycle log is disabled
+func New(filePath string) climate.CycleLog {
+	if filePath == "" {
+		return nil
+	}
+	return &FileCycleLog{filePath: filePath}
+}
+
+// Append adds cycle to the end of the log
+func (l *FileCycleLog) Append(cycle climate.Cycle) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.OpenFile(l.filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	data, err := json.Marshal(cycle)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// Query returns cycles started between from and to
+func (l *FileCycleLog) Query(from, to time.Time) ([]climate.Cycle, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cycles := []climate.Cycle{}
+	file, err := os.Open(l.filePath)
+	if os.IsNotExist(err) {
+		return cycles, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var cycle climate.Cycle
+		if err := json.Unmarshal(scanner.Bytes(), &cycle); err != nil {
+			continue
+		}
+		if cycle.Start.Before(from) || cycle.Start.After(to) {
+			continue
+		}
+		cycles = append(cycles, cycle)
+	}
+	return cycles, scanner.Err()
+}
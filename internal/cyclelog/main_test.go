@@ -0,0 +1,40 @@
+package cyclelog
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/climate"
+)
+
+func TestAppendAndQuery(t *testing.T) {
+	log := New(filepath.Join(t.TempDir(), "cycles.jsonl"))
+
+	cycles, err := log.Query(time.Time{}, time.Now())
+	if err != nil || len(cycles) != 0 {
+		t.Fatalf("Expected empty log, got %v %v", cycles, err)
+	}
+
+	start := time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC)
+	for i, trigger := range []string{climate.TRIGGER_MODEL, climate.TRIGGER_MANUAL, climate.TRIGGER_MODEL} {
+		at := start.Add(time.Duration(i) * time.Hour)
+		if err := log.Append(climate.Cycle{Start: at, End: at.Add(20 * time.Minute), Trigger: trigger}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cycles, err = log.Query(start.Add(30*time.Minute), start.Add(3*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cycles) != 2 || cycles[0].Trigger != climate.TRIGGER_MANUAL {
+		t.Errorf("Unexpected cycles %+v", cycles)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	if log := New(""); log != nil {
+		t.Errorf("Expected nil log for empty path, got %v", log)
+	}
+}
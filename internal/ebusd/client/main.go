@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/ksimuk/ebus-climate/internal/config"
 	"github.com/rs/zerolog/log"
@@ -16,16 +18,50 @@ type Circuit string
 type Client struct {
 	config     *config.Config
 	parameters []string
+	stats      *stats
+}
+
+// Stats are request counters since start
+type Stats struct {
+	Requests uint64
+	Errors   uint64
+	Latency  time.Duration // total time spent in requests
+}
+
+type stats struct {
+	mu sync.Mutex
+	Stats
 }
 
 func New(config *config.Config, readParameters []string) *Client {
 	return &Client{
 		config:     config,
 		parameters: readParameters,
+		stats:      &stats{},
 	}
 }
 
+func (c Client) Stats() Stats {
+	c.stats.mu.Lock()
+	defer c.stats.mu.Unlock()
+	return c.stats.Stats
+}
+
 func (c Client) request(request string) ([]string, error) {
+	start := time.Now()
+	result, err := c.doRequest(request)
+
+	c.stats.mu.Lock()
+	c.stats.Requests++
+	c.stats.Latency += time.Since(start)
+	if err != nil {
+		c.stats.Errors++
+	}
+	c.stats.mu.Unlock()
+	return result, err
+}
+
+func (c Client) doRequest(request string) ([]string, error) {
 	log.Trace().Msgf("Connecting to ebusd at %s port %s", c.config.Ebus.Host, c.config.Ebus.Port)
 	connection, err := net.Dial("tcp", net.JoinHostPort(c.config.Ebus.Host, c.config.Ebus.Port))
 	if err != nil {
@@ -65,12 +101,16 @@ func (c Client) request(request string) ([]string, error) {
 }
 
 func (c Client) read(parameter string, force bool) ([]string, error) {
+	return c.readCircuit(c.config.Ebus.Circuit, parameter, force)
+}
+
+func (c Client) readCircuit(circuit string, parameter string, force bool) ([]string, error) {
 
 	args := ""
 	if force {
 		args = " -f"
 	}
-	request := fmt.Sprintf("read -c %s -m 60 %s%s\n", c.config.Ebus.Circuit, parameter, args)
+	request := fmt.Sprintf("read -c %s -m 60 %s%s\n", circuit, parameter, args)
 
 	reply, err := c.request(request)
 
@@ -106,6 +146,14 @@ func (c Client) Get(parameter string) ([]string, error) {
 	return c.read(parameter, false)
 }
 
+// GetFrom reads parameter from another circuit, e.g. outdoor sensor on the controller
+func (c Client) GetFrom(circuit string, parameter string) ([]string, error) {
+	if circuit == "" {
+		circuit = c.config.Ebus.Circuit
+	}
+	return c.readCircuit(circuit, parameter, false)
+}
+
 func (c Client) Set(parameter string, value string) error {
 	return c.write(parameter, value)
 }
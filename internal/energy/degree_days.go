@@ -0,0 +1,195 @@
+package energy
+
+import (
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const MIN_BASELINE_DAYS = 5 // qualifying days needed before regressions are flagged
+
+// DegreeDay is heating energy against heating degree-days of a day or month
+type DegreeDay struct {
+	Period          string  `json:"period"` // 2006-01-02 or 2006-01
+	Days            int     `json:"days"`
+	AvgOutsideTemp  float64 `json:"avg_outside_temp"`
+	DegreeDays      float64 `json:"degree_days"`
+	Heating         float64 `json:"heating"`            // kWh, boiler counter or estimate when counter is unknown
+	KWhPerDegreeDay float64 `json:"kwh_per_degree_day"` // 0 when there are too few degree-days
+	Baseline        float64 `json:"baseline"`           // kWh per degree-day of preceding days or month
+	Change          float64 `json:"change"`             // relative change against baseline
+	Regression      bool    `json:"regression"`         // efficiency is worse than baseline by more than threshold
+}
+
+type DegreeDayReport struct {
+	Period  string      `json:"period"`
+	Base    float64     `json:"base"` // base temperature
+	Entries []DegreeDay `json:"entries"`
+	Total   DegreeDay   `json:"total"`
+}
+
+// heating returns day heating kWh, boiler counter is preferred over estimate
+func (l *Ledger) heating(day *Day) float64 {
+	if day.HeatingRaw > 0 {
+		return day.HeatingRaw / l.data.Factor
+	}
+	return day.HeatingEstimated
+}
+
+// degreeDay returns day entry without baseline, ok is false when outside temperature is unknown
+func (l *Ledger) degreeDay(day *Day) (DegreeDay, bool) {
+	if day.OutsideMinutes <= 0 {
+		return DegreeDay{}, false
+	}
+	entry := DegreeDay{
+		Period:         day.Date,
+		Days:           1,
+		AvgOutsideTemp: day.OutsideTempSum / day.OutsideMinutes,
+		Heating:        l.heating(day),
+	}
+	if entry.AvgOutsideTemp < l.baseTemp {
+		entry.DegreeDays = l.baseTemp - entry.AvgOutsideTemp
+	}
+	return entry, true
+}
+
+// compare sets efficiency and flags regression against baseline ratio
+func (l *Ledger) compare(entry *DegreeDay, baseline float64, minDegreeDays float64) {
+	if entry.DegreeDays >= minDegreeDays && entry.DegreeDays > 0 {
+		entry.KWhPerDegreeDay = entry.Heating / entry.DegreeDays
+	}
+	entry.Baseline = baseline
+	if baseline <= 0 || entry.KWhPerDegreeDay <= 0 {
+		return
+	}
+	entry.Change = entry.KWhPerDegreeDay/baseline - 1
+	entry.Regression = entry.Change > l.regressionThreshold
+}
+
+// dailyDegreeDays returns all days with known outside temperature in order, with baselines
+func (l *Ledger) dailyDegreeDays() []DegreeDay {
+	dates := make([]string, 0, len(l.data.Days))
+	for date := range l.data.Days {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	entries := []DegreeDay{}
+	for _, date := range dates {
+		entry, ok := l.degreeDay(l.data.Days[date])
+		if !ok {
+			continue
+		}
+		// baseline is ratio of sums over qualifying days in preceding window
+		day, _ := time.ParseInLocation(DAY_FORMAT, date, time.Local)
+		windowStart := day.AddDate(0, 0, -l.baselineDays).Format(DAY_FORMAT)
+		heating, degreeDays, count := 0.0, 0.0, 0
+		for i := len(entries) - 1; i >= 0 && entries[i].Period >= windowStart; i-- {
+			if entries[i].DegreeDays < l.minDegreeDays {
+				continue
+			}
+			heating += entries[i].Heating
+			degreeDays += entries[i].DegreeDays
+			count++
+		}
+		baseline := 0.0
+		if count >= MIN_BASELINE_DAYS {
+			baseline = heating / degreeDays
+		}
+		l.compare(&entry, baseline, l.minDegreeDays)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// DegreeDays returns per day or per month degree-day report for days in [from, to)
+func (l *Ledger) DegreeDays(period string, from, to time.Time) DegreeDayReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if period != PERIOD_MONTH {
+		period = PERIOD_DAY
+	}
+	report := DegreeDayReport{Period: period, Base: l.baseTemp, Entries: []DegreeDay{}}
+
+	daily := l.dailyDegreeDays()
+	if period == PERIOD_DAY {
+		fromDate := from.Local().Format(DAY_FORMAT)
+		toDate := to.Local().Format(DAY_FORMAT)
+		for _, entry := range daily {
+			if entry.Period >= fromDate && entry.Period < toDate {
+				report.Entries = append(report.Entries, entry)
+			}
+		}
+	} else {
+		// months are compared with previous month, so all months are aggregated first
+		fromMonth := from.Local().Format(MONTH_FORMAT)
+		lastMonth := to.Local().AddDate(0, 0, -1).Format(MONTH_FORMAT)
+		months := []DegreeDay{}
+		for _, entry := range daily {
+			month := entry.Period[:len(MONTH_FORMAT)]
+			if len(months) == 0 || months[len(months)-1].Period != month {
+				months = append(months, DegreeDay{Period: month})
+			}
+			addDegreeDay(&months[len(months)-1], entry)
+		}
+		for i := range months {
+			baseline := 0.0
+			if i > 0 {
+				baseline = months[i-1].KWhPerDegreeDay
+			}
+			finishDegreeDay(&months[i])
+			l.compare(&months[i], baseline, l.minDegreeDays*float64(months[i].Days))
+			if months[i].Period >= fromMonth && months[i].Period <= lastMonth {
+				report.Entries = append(report.Entries, months[i])
+			}
+		}
+	}
+
+	report.Total.Period = "total"
+	for _, entry := range report.Entries {
+		addDegreeDay(&report.Total, entry)
+	}
+	finishDegreeDay(&report.Total)
+	if report.Total.DegreeDays > 0 {
+		report.Total.KWhPerDegreeDay = report.Total.Heating / report.Total.DegreeDays
+	}
+	return report
+}
+
+// addDegreeDay accumulates entry, average temperature is kept as sum until finishDegreeDay
+func addDegreeDay(total *DegreeDay, entry DegreeDay) {
+	total.Days += entry.Days
+	total.AvgOutsideTemp += entry.AvgOutsideTemp * float64(entry.Days)
+	total.DegreeDays += entry.DegreeDays
+	total.Heating += entry.Heating
+}
+
+func finishDegreeDay(total *DegreeDay) {
+	if total.Days > 0 {
+		total.AvgOutsideTemp /= float64(total.Days)
+	}
+}
+
+// LastDegreeDay returns efficiency of the last finished day
+func (l *Ledger) LastDegreeDay() (DegreeDay, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastDegreeDay, l.lastDegreeDayValid
+}
+
+// finishDay evaluates efficiency of finished day and warns on regression
+func (l *Ledger) finishDay(date string) {
+	for _, entry := range l.dailyDegreeDays() {
+		if entry.Period != date {
+			continue
+		}
+		l.lastDegreeDay = entry
+		l.lastDegreeDayValid = true
+		if entry.Regression {
+			log.Warn().Msgf("Heating efficiency regressed on %s: %.2f kWh per degree-day, baseline %.2f (%+.0f%%)",
+				date, entry.KWhPerDegreeDay, entry.Baseline, entry.Change*100)
+		}
+	}
+}
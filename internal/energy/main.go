@@ -0,0 +1,226 @@
+// Package energy keeps a ledger of heating and hot water energy per day,
+// from the power x burn time estimate and from boiler counters, and its cost.
+package energy
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+const DAY_FORMAT = "2006-01-02"
+const MONTH_FORMAT = "2006-01"
+const SAVE_INTERVAL = 10              // records between saves
+const MIN_CALIBRATION_ENERGY = 5.0    // kWh of estimated heating before factor is calibrated
+const CALIBRATION_LEARNING_RATE = 0.2 // weight of the latest day in calibrated factor
+
+// Day is energy used in one day
+type Day struct {
+	Date             string  `json:"date"`
+	HeatingEstimated float64 `json:"heating_estimated"` // kWh from power x burn time
+	HeatingRaw       float64 `json:"heating_raw"`       // boiler heating counter delta
+	HotWaterRaw      float64 `json:"hot_water_raw"`     // boiler hot water counter delta
+	BurnMinutes      float64 `json:"burn_minutes"`
+	UnitCost         float64 `json:"unit_cost"`        // cost of energy without standing charge
+	Calibrated       bool    `json:"calibrated"`       // day was used to calibrate counter factor
+	OutsideTempSum   float64 `json:"outside_temp_sum"` // outside temperature x minutes, for daily average
+	OutsideMinutes   float64 `json:"outside_minutes"`  // minutes with known outside temperature
+}
+
+// Sample is a per minute reading from the boiler
+type Sample struct {
+	Time        time.Time
+	HeatingRaw  float64 // boiler counter, negative if unknown
+	HotWaterRaw float64 // boiler counter, negative if unknown
+	Burning     bool
+	OutsideTemp float64
+	OutsideOK   bool          // outside temperature is fresh
+	Power       float64       // boiler power in W
+	Interval    time.Duration // time since previous sample
+}
+
+type ledgerData struct {
+	Factor          float64         `json:"factor"` // boiler counter units per kWh
+	Days            map[string]*Day `json:"days"`
+	LastHeatingRaw  float64         `json:"last_heating_raw"`
+	LastHotWaterRaw float64         `json:"last_hot_water_raw"`
+}
+
+type Ledger struct {
+	filePath  string
+	tariff    *Tariff
+	calibrate bool
+
+	baseTemp            float64 // degree-day base temperature
+	baselineDays        int
+	minDegreeDays       float64
+	regressionThreshold float64
+
+	mu                 sync.Mutex
+	data               ledgerData
+	pending            int
+	currentDate        string
+	lastDegreeDay      DegreeDay // efficiency of last finished day
+	lastDegreeDayValid bool
+}
+
+// New loads ledger from file, defaultFactor converts boiler counters to kWh until calibrated
+func New(config *config.Config, defaultFactor float64) *Ledger {
+	l := &Ledger{
+		filePath:  config.Energy.File,
+		tariff:    NewTariff(config),
+		calibrate: config.Energy.Calibrate,
+
+		baseTemp:            config.DegreeDays.Base,
+		baselineDays:        config.DegreeDays.BaselineDays,
+		minDegreeDays:       config.DegreeDays.MinDegreeDays,
+		regressionThreshold: config.DegreeDays.RegressionThreshold,
+		data: ledgerData{
+			Factor:          defaultFactor,
+			Days:            map[string]*Day{},
+			LastHeatingRaw:  -1,
+			LastHotWaterRaw: -1,
+		},
+	}
+	if l.filePath == "" {
+		return l
+	}
+	data, err := os.ReadFile(l.filePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Error().Err(err).Msg("Failed to read energy ledger")
+		}
+		return l
+	}
+	if err := json.Unmarshal(data, &l.data); err != nil {
+		log.Error().Err(err).Msg("Failed to parse energy ledger")
+	}
+	if l.data.Days == nil {
+		l.data.Days = map[string]*Day{}
+	}
+	if !l.calibrate || l.data.Factor <= 0 {
+		l.data.Factor = defaultFactor
+	}
+	return l
+}
+
+func (l *Ledger) day(t time.Time) *Day {
+	date := t.Local().Format(DAY_FORMAT)
+	day, ok := l.data.Days[date]
+	if !ok {
+		day = &Day{Date: date}
+		l.data.Days[date] = day
+	}
+	return day
+}
+
+// counterDelta returns increase of boiler counter, 0 if unknown or reset
+func counterDelta(last *float64, current float64) float64 {
+	previous := *last
+	if current < 0 {
+		return 0
+	}
+	*last = current
+	if previous < 0 || current < previous {
+		return 0
+	}
+	return current - previous
+}
+
+// Record adds a sample to the ledger
+func (l *Ledger) Record(sample Sample) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	day := l.day(sample.Time)
+	if l.currentDate != day.Date {
+		previous := l.currentDate
+		if previous == "" {
+			previous = sample.Time.Local().AddDate(0, 0, -1).Format(DAY_FORMAT)
+		}
+		l.currentDate = day.Date
+		l.calibrateFactor(day.Date)
+		l.finishDay(previous)
+	}
+
+	if sample.OutsideOK {
+		day.OutsideTempSum += sample.OutsideTemp * sample.Interval.Minutes()
+		day.OutsideMinutes += sample.Interval.Minutes()
+	}
+
+	estimated := 0.0
+	if sample.Burning {
+		minutes := sample.Interval.Minutes()
+		day.BurnMinutes += minutes
+		estimated = sample.Power * minutes / 60 / 1000
+		day.HeatingEstimated += estimated
+	}
+	heatingRaw := counterDelta(&l.data.LastHeatingRaw, sample.HeatingRaw)
+	hotWaterRaw := counterDelta(&l.data.LastHotWaterRaw, sample.HotWaterRaw)
+	day.HeatingRaw += heatingRaw
+	day.HotWaterRaw += hotWaterRaw
+
+	// cost from boiler counters when available, estimate otherwise
+	used := (heatingRaw + hotWaterRaw) / l.data.Factor
+	if sample.HeatingRaw < 0 {
+		used = estimated
+	}
+	day.UnitCost += used * l.tariff.Price(sample.Time)
+
+	l.pending++
+	if l.pending >= SAVE_INTERVAL {
+		l.saveLocked()
+	}
+}
+
+// calibrateFactor updates conversion factor from finished days before today.
+// Estimate assumes full configured power, so a modulating boiler is aligned to nominal
+// power rather than measured; the factor is used only in ledger reports.
+func (l *Ledger) calibrateFactor(today string) {
+	if !l.calibrate {
+		return
+	}
+	for date, day := range l.data.Days {
+		if date >= today || day.HeatingEstimated < MIN_CALIBRATION_ENERGY || day.HeatingRaw <= 0 || day.Calibrated {
+			continue
+		}
+		observed := day.HeatingRaw / day.HeatingEstimated
+		l.data.Factor = l.data.Factor*(1-CALIBRATION_LEARNING_RATE) + observed*CALIBRATION_LEARNING_RATE
+		day.Calibrated = true
+		log.Info().Msgf("Calibrated boiler counter factor to %f from %s (observed %f)", l.data.Factor, date, observed)
+	}
+}
+
+// Factor returns boiler counter units per kWh
+func (l *Ledger) Factor() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.data.Factor
+}
+
+// Save writes ledger to file
+func (l *Ledger) Save() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.saveLocked()
+}
+
+func (l *Ledger) saveLocked() error {
+	l.pending = 0
+	if l.filePath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(l.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(l.filePath, data, 0644); err != nil {
+		log.Error().Err(err).Msg("Failed to save energy ledger")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package energy
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/config"
+)
+
+func testConfig(t *testing.T) *config.Config {
+	cfg := &config.Config{}
+	cfg.Energy.File = filepath.Join(t.TempDir(), "energy.json")
+	cfg.Energy.StandingCharge = 0.3
+	cfg.Energy.UnitPrice = 0.1
+	cfg.Energy.TimeOfUse = []config.TariffRate{
+		{Time: "00:00", Price: 0.05},
+		{Time: "06:00", Price: 0.1},
+	}
+	return cfg
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestRecordAndReport(t *testing.T) {
+	cfg := testConfig(t)
+	l := New(cfg, 100)
+	day := time.Date(2025, 1, 10, 0, 0, 0, 0, time.Local)
+
+	// one hour burning at night, counters increase by 10 kWh heating and 2 kWh hot water
+	for i := 0; i <= 60; i++ {
+		l.Record(Sample{
+			Time:        day.Add(time.Hour + time.Duration(i)*time.Minute),
+			HeatingRaw:  float64(1000 + i*1000/60),
+			HotWaterRaw: float64(500 + i*200/60),
+			Burning:     i > 0,
+			Power:       10000,
+			Interval:    time.Minute,
+		})
+	}
+
+	report := l.Report(PERIOD_DAY, day, day.AddDate(0, 0, 1))
+	if len(report.Entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(report.Entries))
+	}
+	entry := report.Entries[0]
+	if !near(entry.HeatingEstimated, 10) {
+		t.Errorf("expected 10 kWh estimated, got %f", entry.HeatingEstimated)
+	}
+	if !near(entry.HeatingBoiler, 10) || !near(entry.HotWaterBoiler, 2) {
+		t.Errorf("expected 10/2 kWh from boiler, got %f/%f", entry.HeatingBoiler, entry.HotWaterBoiler)
+	}
+	// 12 kWh at night rate plus standing charge
+	if !near(entry.Cost, 12*0.05+0.3) {
+		t.Errorf("unexpected cost %f", entry.Cost)
+	}
+
+	if err := l.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded := New(cfg, 100)
+	month := loaded.Report(PERIOD_MONTH, day.AddDate(0, 0, -9), day.AddDate(0, 1, 0))
+	if len(month.Entries) != 1 || month.Entries[0].Period != "2025-01" || !near(month.Total.HeatingBoiler, 10) {
+		t.Errorf("unexpected month report %+v", month)
+	}
+}
+
+func TestCalibration(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Energy.Calibrate = true
+	l := New(cfg, 100)
+	day := time.Date(2025, 1, 10, 12, 0, 0, 0, time.Local)
+
+	// counter reports 20 kWh at default factor, estimate is 10 kWh
+	for i := 0; i <= 60; i++ {
+		l.Record(Sample{
+			Time:        day.Add(time.Duration(i) * time.Minute),
+			HeatingRaw:  float64(i * 2000 / 60),
+			HotWaterRaw: -1,
+			Burning:     i > 0,
+			Power:       10000,
+			Interval:    time.Minute,
+		})
+	}
+	if l.Factor() != 100 {
+		t.Fatalf("factor should not change before the day ends, got %f", l.Factor())
+	}
+	l.Record(Sample{Time: day.AddDate(0, 0, 1), HeatingRaw: 2000, HotWaterRaw: -1, Interval: time.Minute})
+	expected := 100*(1-CALIBRATION_LEARNING_RATE) + 200*CALIBRATION_LEARNING_RATE
+	if !near(l.Factor(), expected) {
+		t.Errorf("expected factor %f, got %f", expected, l.Factor())
+	}
+	l.Record(Sample{Time: day.AddDate(0, 0, 1).Add(time.Minute), HeatingRaw: 2000, HotWaterRaw: -1, Interval: time.Minute})
+	if !near(l.Factor(), expected) {
+		t.Errorf("day should be used for calibration once, got %f", l.Factor())
+	}
+}
+
+func TestDegreeDaysRegression(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.DegreeDays.Base = 15.5
+	cfg.DegreeDays.BaselineDays = 28
+	cfg.DegreeDays.MinDegreeDays = 2
+	cfg.DegreeDays.RegressionThreshold = 0.25
+	l := New(cfg, 100)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)
+
+	// 10 degree-days a day at 5.5C, 5 kWh per degree-day, last day uses 8 kWh per degree-day
+	for d := 0; d < 8; d++ {
+		perDegreeDay := 5.0
+		if d == 7 {
+			perDegreeDay = 8
+		}
+		day := start.AddDate(0, 0, d)
+		for m := 0; m < 24*60; m += 60 {
+			l.Record(Sample{
+				Time:        day.Add(time.Duration(m) * time.Minute),
+				HeatingRaw:  -1,
+				HotWaterRaw: -1,
+				Burning:     true,
+				Power:       perDegreeDay * 10 * 1000 / 24, // W over hourly samples
+				OutsideTemp: 5.5,
+				OutsideOK:   true,
+				Interval:    time.Hour,
+			})
+		}
+	}
+
+	report := l.DegreeDays(PERIOD_DAY, start, start.AddDate(0, 0, 8))
+	if len(report.Entries) != 8 {
+		t.Fatalf("expected 8 entries, got %d", len(report.Entries))
+	}
+	last := report.Entries[7]
+	if !near(last.DegreeDays, 10) || !near(last.KWhPerDegreeDay, 8) || !near(last.Baseline, 5) {
+		t.Errorf("unexpected last day %+v", last)
+	}
+	if !last.Regression || report.Entries[6].Regression {
+		t.Errorf("only last day should be flagged, got %+v", report.Entries)
+	}
+
+	l.Record(Sample{Time: start.AddDate(0, 0, 8), HeatingRaw: -1, HotWaterRaw: -1, Interval: time.Minute})
+	if day, ok := l.LastDegreeDay(); !ok || day.Period != "2025-01-08" || !day.Regression {
+		t.Errorf("expected regression of finished day, got %+v", day)
+	}
+
+	month := l.DegreeDays(PERIOD_MONTH, start, start.AddDate(0, 1, 0))
+	if len(month.Entries) != 1 || !near(month.Entries[0].DegreeDays, 80) {
+		t.Errorf("unexpected month summary %+v", month)
+	}
+}
@@ -0,0 +1,86 @@
+package energy
+
+import (
+	"sort"
+	"time"
+)
+
+const PERIOD_DAY = "day"
+const PERIOD_MONTH = "month"
+
+// Entry is energy and cost of a day or month
+type Entry struct {
+	Period           string  `json:"period"` // 2006-01-02 or 2006-01
+	Days             int     `json:"days"`
+	HeatingEstimated float64 `json:"heating_estimated"` // kWh from power x burn time
+	HeatingBoiler    float64 `json:"heating_boiler"`    // kWh from boiler counter
+	HotWaterBoiler   float64 `json:"hot_water_boiler"`  // kWh from boiler counter
+	BurnMinutes      float64 `json:"burn_minutes"`
+	UnitCost         float64 `json:"unit_cost"`
+	StandingCharge   float64 `json:"standing_charge"`
+	Cost             float64 `json:"cost"`
+}
+
+type Report struct {
+	Period  string  `json:"period"`
+	Factor  float64 `json:"factor"` // boiler counter units per kWh
+	Entries []Entry `json:"entries"`
+	Total   Entry   `json:"total"`
+}
+
+func (e *Entry) add(other Entry) {
+	e.Days += other.Days
+	e.HeatingEstimated += other.HeatingEstimated
+	e.HeatingBoiler += other.HeatingBoiler
+	e.HotWaterBoiler += other.HotWaterBoiler
+	e.BurnMinutes += other.BurnMinutes
+	e.UnitCost += other.UnitCost
+	e.StandingCharge += other.StandingCharge
+	e.Cost += other.Cost
+}
+
+// Report returns per day or per month entries for days in [from, to)
+func (l *Ledger) Report(period string, from, to time.Time) Report {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if period != PERIOD_MONTH {
+		period = PERIOD_DAY
+	}
+	fromDate := from.Local().Format(DAY_FORMAT)
+	toDate := to.Local().Format(DAY_FORMAT)
+	report := Report{Period: period, Factor: l.data.Factor, Entries: []Entry{}}
+	entries := map[string]*Entry{}
+	for date, day := range l.data.Days {
+		if date < fromDate || date >= toDate {
+			continue
+		}
+		key := date
+		if period == PERIOD_MONTH {
+			key = date[:len(MONTH_FORMAT)]
+		}
+		entry, ok := entries[key]
+		if !ok {
+			entry = &Entry{Period: key}
+			entries[key] = entry
+		}
+		entry.add(Entry{
+			Days:             1,
+			HeatingEstimated: day.HeatingEstimated,
+			HeatingBoiler:    day.HeatingRaw / l.data.Factor,
+			HotWaterBoiler:   day.HotWaterRaw / l.data.Factor,
+			BurnMinutes:      day.BurnMinutes,
+			UnitCost:         day.UnitCost,
+			StandingCharge:   l.tariff.standingCharge,
+			Cost:             day.UnitCost + l.tariff.standingCharge,
+		})
+	}
+	for _, entry := range entries {
+		report.Entries = append(report.Entries, *entry)
+		report.Total.add(*entry)
+	}
+	sort.Slice(report.Entries, func(i, j int) bool {
+		return report.Entries[i].Period < report.Entries[j].Period
+	})
+	return report
+}
@@ -0,0 +1,46 @@
+package energy
+
+import (
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/ksimuk/ebus-climate/internal/schedule"
+	"github.com/rs/zerolog/log"
+)
+
+// Tariff is gas price with optional time-of-use periods
+type Tariff struct {
+	standingCharge float64 // per day
+	unitPrice      float64 // per kWh outside of time-of-use periods
+	periods        *schedule.Schedule
+	prices         []float64
+}
+
+func NewTariff(config *config.Config) *Tariff {
+	cfg := config.Energy
+	t := &Tariff{
+		standingCharge: cfg.StandingCharge,
+		unitPrice:      cfg.UnitPrice,
+	}
+	points := []schedule.Point{}
+	for i, p := range cfg.TimeOfUse {
+		point, err := schedule.NewPoint(i, p.Days, p.Time)
+		if err != nil {
+			log.Error().Err(err).Msgf("Ignoring invalid time-of-use period %d", i)
+			t.prices = append(t.prices, cfg.UnitPrice)
+			continue
+		}
+		points = append(points, point)
+		t.prices = append(t.prices, p.Price)
+	}
+	t.periods = schedule.New(points)
+	return t
+}
+
+// Price returns unit price per kWh at time t
+func (t *Tariff) Price(at time.Time) float64 {
+	if active, ok := t.periods.Active(at); ok {
+		return t.prices[active.Index]
+	}
+	return t.unitPrice
+}
@@ -0,0 +1,212 @@
+// Package history is an embedded time-series log: per minute records are appended
+// to daily files, finished days are downsampled and old files removed by retention.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+const RAW_DIR = "raw"
+const DOWNSAMPLED_DIR = "downsampled"
+const DAY_FORMAT = "2006-01-02"
+const FILE_EXT = ".jsonl"
+
+// Record is a set of values at a moment in time
+type Record struct {
+	Time   time.Time          `json:"t"`
+	Values map[string]float64 `json:"v"`
+}
+
+type Store struct {
+	dir             string
+	rawRetention    int // days
+	retention       int // days
+	downsample      time.Duration
+	mu              sync.Mutex
+	currentDay      string
+	file            *os.File
+	lastMaintenance time.Time
+}
+
+// New creates store from config, nil if history is disabled
+func New(config *config.Config) (*Store, error) {
+	cfg := config.History
+	if cfg.Path == "" {
+		return nil, nil
+	}
+	s := &Store{
+		dir:          cfg.Path,
+		rawRetention: cfg.RawRetention,
+		retention:    cfg.Retention,
+		downsample:   time.Duration(cfg.Downsample * float64(time.Minute)),
+	}
+	if s.downsample <= 0 {
+		s.downsample = 15 * time.Minute
+	}
+	for _, dir := range []string{RAW_DIR, DOWNSAMPLED_DIR} {
+		if err := os.MkdirAll(filepath.Join(s.dir, dir), 0755); err != nil {
+			return nil, err
+		}
+	}
+	s.maintain(time.Now())
+	return s, nil
+}
+
+func (s *Store) path(dir string, day string) string {
+	return filepath.Join(s.dir, dir, day+FILE_EXT)
+}
+
+// Append writes record to the file of its day
+func (s *Store) Append(record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := record.Time.Local().Format(DAY_FORMAT)
+	if day != s.currentDay || s.file == nil {
+		if s.file != nil {
+			s.file.Close()
+			s.file = nil
+		}
+		file, err := os.OpenFile(s.path(RAW_DIR, day), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		s.file = file
+		s.currentDay = day
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	if record.Time.Sub(s.lastMaintenance) > time.Hour {
+		s.maintainLocked(record.Time)
+	}
+	return nil
+}
+
+// Close closes current file
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func (s *Store) maintain(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maintainLocked(now)
+}
+
+// maintainLocked downsamples finished days and removes files past retention
+func (s *Store) maintainLocked(now time.Time) {
+	s.lastMaintenance = now
+	today := now.Local().Format(DAY_FORMAT)
+
+	for _, day := range s.days(RAW_DIR) {
+		if day >= today {
+			continue
+		}
+		if _, err := os.Stat(s.path(DOWNSAMPLED_DIR, day)); os.IsNotExist(err) {
+			if err := s.downsampleDay(day); err != nil {
+				log.Error().Err(err).Msgf("Failed to downsample history of %s", day)
+			}
+		}
+		if s.expired(day, s.rawRetention, now) {
+			os.Remove(s.path(RAW_DIR, day))
+		}
+	}
+	for _, day := range s.days(DOWNSAMPLED_DIR) {
+		if s.expired(day, s.retention, now) {
+			os.Remove(s.path(DOWNSAMPLED_DIR, day))
+		}
+	}
+}
+
+func (s *Store) expired(day string, retention int, now time.Time) bool {
+	if retention <= 0 {
+		return false
+	}
+	t, err := time.ParseInLocation(DAY_FORMAT, day, time.Local)
+	if err != nil {
+		return false
+	}
+	return now.Sub(t) > time.Duration(retention+1)*24*time.Hour
+}
+
+// days returns sorted days which have files in dir
+func (s *Store) days(dir string) []string {
+	entries, err := os.ReadDir(filepath.Join(s.dir, dir))
+	if err != nil {
+		return nil
+	}
+	days := []string{}
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), FILE_EXT); ok {
+			days = append(days, name)
+		}
+	}
+	sort.Strings(days)
+	return days
+}
+
+func (s *Store) downsampleDay(day string) error {
+	records, err := readFile(s.path(RAW_DIR, day))
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(s.path(DOWNSAMPLED_DIR, day))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	for _, record := range aggregate(records, s.downsample, nil) {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		writer.Write(append(data, '\n'))
+	}
+	log.Debug().Msgf("Downsampled history of %s", day)
+	return writer.Flush()
+}
+
+func readFile(path string) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records := []Record{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// skip partially written line
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
@@ -0,0 +1,94 @@
+package history
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/config"
+)
+
+func newTestStore(t *testing.T) *Store {
+	cfg := &config.Config{}
+	cfg.History.Path = t.TempDir()
+	cfg.History.RawRetention = 2
+	cfg.History.Retention = 30
+	cfg.History.Downsample = 15
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestAppendAndQuery(t *testing.T) {
+	s := newTestStore(t)
+	start := time.Now().Add(-time.Hour).Truncate(time.Hour)
+	for i := 0; i < 30; i++ {
+		s.Append(Record{Time: start.Add(time.Duration(i) * time.Minute), Values: map[string]float64{
+			"inside_temp": float64(i),
+			"flow_temp":   50,
+		}})
+	}
+
+	records, err := s.Query(start, start.Add(9*time.Minute), []string{"inside_temp"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 10 {
+		t.Fatalf("Expected 10 records, got %d", len(records))
+	}
+	if _, ok := records[0].Values["flow_temp"]; ok {
+		t.Error("Expected only selected fields")
+	}
+
+	records, _ = s.Query(start, start.Add(time.Hour), nil, 15*time.Minute)
+	if len(records) != 2 || records[0].Values["inside_temp"] != 7 || records[1].Values["inside_temp"] != 22 {
+		t.Errorf("Unexpected aggregated records %+v", records)
+	}
+}
+
+func TestDownsampleAndRetention(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now()
+	day := time.Date(now.Year(), now.Month(), now.Day()-4, 0, 0, 0, 0, time.Local)
+	name := day.Format(DAY_FORMAT)
+	for i := 0; i < 60; i++ {
+		s.Append(Record{Time: day.Add(time.Duration(i) * time.Minute), Values: map[string]float64{"outside_temp": 1}})
+	}
+	s.Close()
+
+	// four days later raw file is past retention, downsampled data is kept
+	s.maintain(now)
+	if _, err := os.Stat(s.path(RAW_DIR, name)); !os.IsNotExist(err) {
+		t.Error("Expected raw file to be removed")
+	}
+	records, err := s.Query(day, day.Add(time.Hour), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 || records[0].Values["outside_temp"] != 1 {
+		t.Errorf("Expected 4 downsampled records, got %+v", records)
+	}
+}
+
+func TestQueryClamp(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now()
+	s.Append(Record{Time: now.Add(-time.Minute), Values: map[string]float64{"inside_temp": 20}})
+
+	// unbounded range is limited to retention window and now
+	records, err := s.Query(time.Time{}, now.AddDate(100, 0, 0), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Errorf("Expected 1 record, got %+v", records)
+	}
+
+	s.retention = 0 // unlimited retention starts at first file
+	if oldest := s.oldest(now); oldest.Format(DAY_FORMAT) != now.Add(-time.Minute).Format(DAY_FORMAT) {
+		t.Errorf("Expected oldest at first file, got %s", oldest)
+	}
+}
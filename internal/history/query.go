@@ -0,0 +1,115 @@
+package history
+
+import (
+	"os"
+	"time"
+)
+
+// Query returns records between from and to with selected fields (all if empty),
+// averaged into buckets of step (raw records if step is 0).
+// Files are read without the append lock, partially written lines are skipped.
+func (s *Store) Query(from, to time.Time, fields []string, step time.Duration) ([]Record, error) {
+	now := time.Now()
+	if to.After(now) {
+		to = now
+	}
+	if oldest := s.oldest(now); from.Before(oldest) {
+		from = oldest
+	}
+
+	records := []Record{}
+	for day := from.Local(); !day.After(to.Local().Add(24 * time.Hour)); day = day.AddDate(0, 0, 1) {
+		name := day.Format(DAY_FORMAT)
+		dayRecords, err := readFile(s.path(RAW_DIR, name))
+		if os.IsNotExist(err) {
+			dayRecords, err = readFile(s.path(DOWNSAMPLED_DIR, name))
+		}
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range dayRecords {
+			if record.Time.Before(from) || record.Time.After(to) {
+				continue
+			}
+			records = append(records, record)
+		}
+	}
+	return aggregate(records, step, fields), nil
+}
+
+// oldest returns time before which no records are kept, by retention or first file on disk
+func (s *Store) oldest(now time.Time) time.Time {
+	if s.retention > 0 && s.rawRetention > 0 {
+		return now.AddDate(0, 0, -(max(s.retention, s.rawRetention) + 1))
+	}
+	oldest := now
+	for _, dir := range []string{RAW_DIR, DOWNSAMPLED_DIR} {
+		days := s.days(dir)
+		if len(days) == 0 {
+			continue
+		}
+		if t, err := time.ParseInLocation(DAY_FORMAT, days[0], time.Local); err == nil && t.Before(oldest) {
+			oldest = t
+		}
+	}
+	return oldest
+}
+
+// aggregate averages records into buckets of step and keeps only selected fields
+func aggregate(records []Record, step time.Duration, fields []string) []Record {
+	selected := map[string]bool{}
+	for _, field := range fields {
+		selected[field] = true
+	}
+	keep := func(field string) bool {
+		return len(selected) == 0 || selected[field]
+	}
+
+	result := []Record{}
+	if step <= 0 {
+		for _, record := range records {
+			values := map[string]float64{}
+			for field, value := range record.Values {
+				if keep(field) {
+					values[field] = value
+				}
+			}
+			result = append(result, Record{Time: record.Time, Values: values})
+		}
+		return result
+	}
+
+	var bucket time.Time
+	sums := map[string]float64{}
+	counts := map[string]int{}
+	flush := func() {
+		if len(counts) == 0 {
+			return
+		}
+		values := map[string]float64{}
+		for field, sum := range sums {
+			values[field] = sum / float64(counts[field])
+		}
+		result = append(result, Record{Time: bucket, Values: values})
+		sums = map[string]float64{}
+		counts = map[string]int{}
+	}
+	for _, record := range records {
+		start := record.Time.Truncate(step)
+		if !start.Equal(bucket) {
+			flush()
+			bucket = start
+		}
+		for field, value := range record.Values {
+			if keep(field) {
+				sums[field] += value
+				counts[field]++
+			}
+		}
+	}
+	flush()
+	return result
+}
@@ -0,0 +1,127 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const HA_MODE_HEAT = "heat"
+const HA_MODE_OFF = "off"
+
+// entity is Home Assistant discovery config published to <discovery>/<component>/<node>/<object>/config
+type entity struct {
+	component string
+	objectID  string
+	config    map[string]any
+}
+
+type sensor struct {
+	objectID    string
+	name        string
+	field       string // field of state payload
+	unit        string
+	deviceClass string
+	stateClass  string
+}
+
+var SENSORS = []sensor{
+	{"flow_temp", "Flow temperature", "flow_temp", "°C", "temperature", "measurement"},
+	{"return_temp", "Return temperature", "return_temp", "°C", "temperature", "measurement"},
+	{"outside_temp", "Outside temperature", "outside_temp", "°C", "temperature", "measurement"},
+	{"water_pressure", "Water pressure", "water_pressure", "bar", "pressure", "measurement"},
+	{"heat_loss_balance", "Heat loss balance", "heat_loss_balance", "Wh", "", "measurement"},
+	{"heat_loss", "Heat loss", "heat_loss", "W", "power", "measurement"},
+	{"energy_heating", "Heating energy", "energy_heating", "kWh", "energy", "total_increasing"},
+	{"energy_hot_water", "Hot water energy", "energy_hot_water", "kWh", "energy", "total_increasing"},
+	{"consumption_heating", "Estimated heating consumption", "consumption_heating", "kWh", "energy", "total_increasing"},
+}
+
+func (b *Bridge) device() map[string]any {
+	return map[string]any{
+		"identifiers":  []string{b.nodeID},
+		"name":         b.name,
+		"manufacturer": "ebus-climate",
+		"model":        "eBUS boiler controller",
+	}
+}
+
+func (b *Bridge) uniqueID(objectID string) string {
+	return fmt.Sprintf("%s_%s", b.nodeID, objectID)
+}
+
+// entities returns discovery configs of climate entity, hot water target and sensors
+func (b *Bridge) entities() []entity {
+	common := func(objectID, name string) map[string]any {
+		return map[string]any{
+			"name":               name,
+			"unique_id":          b.uniqueID(objectID),
+			"object_id":          b.uniqueID(objectID),
+			"availability_topic": b.topic(TOPIC_AVAILABILITY),
+			"device":             b.device(),
+		}
+	}
+
+	thermostat := common("thermostat", "Heating")
+	thermostat["modes"] = []string{HA_MODE_OFF, HA_MODE_HEAT}
+	thermostat["mode_state_topic"] = b.topic(TOPIC_STATE)
+	thermostat["mode_state_template"] = "{{ value_json.mode }}"
+	thermostat["mode_command_topic"] = b.topic(TOPIC_MODE_SET)
+	thermostat["action_topic"] = b.topic(TOPIC_STATE)
+	thermostat["action_template"] = "{{ value_json.action }}"
+	thermostat["temperature_state_topic"] = b.topic(TOPIC_STATE)
+	thermostat["temperature_state_template"] = "{{ value_json.target_temperature }}"
+	thermostat["temperature_command_topic"] = b.topic(TOPIC_TARGET_SET)
+	thermostat["current_temperature_topic"] = b.topic(TOPIC_STATE)
+	thermostat["current_temperature_template"] = "{{ value_json.inside_temp }}"
+	thermostat["min_temp"] = b.limits.MinTargetTemp
+	thermostat["max_temp"] = b.limits.MaxTargetTemp
+	thermostat["temp_step"] = 0.5
+	thermostat["temperature_unit"] = "C"
+
+	hotWater := common("hw_target_temp", "Hot water target")
+	hotWater["command_topic"] = b.topic(TOPIC_HW_TARGET_SET)
+	hotWater["state_topic"] = b.topic(TOPIC_STATE)
+	hotWater["value_template"] = "{{ value_json.hw_target_temp }}"
+	hotWater["min"] = b.limits.MinHWTargetTemp
+	hotWater["max"] = b.limits.MaxHWTargetTemp
+	hotWater["step"] = 1
+	hotWater["unit_of_measurement"] = "°C"
+	hotWater["device_class"] = "temperature"
+
+	burner := common("burner", "Burner")
+	burner["state_topic"] = b.topic(TOPIC_STATE)
+	burner["value_template"] = "{{ 'ON' if value_json.gas_active else 'OFF' }}"
+	burner["device_class"] = "heat"
+
+	entities := []entity{
+		{"climate", "thermostat", thermostat},
+		{"number", "hw_target_temp", hotWater},
+		{"binary_sensor", "burner", burner},
+	}
+	for _, s := range SENSORS {
+		config := common(s.objectID, s.name)
+		config["state_topic"] = b.topic(TOPIC_STATE)
+		config["value_template"] = fmt.Sprintf("{{ value_json.%s }}", s.field)
+		config["unit_of_measurement"] = s.unit
+		if s.deviceClass != "" {
+			config["device_class"] = s.deviceClass
+		}
+		config["state_class"] = s.stateClass
+		entities = append(entities, entity{"sensor", s.objectID, config})
+	}
+	return entities
+}
+
+func (b *Bridge) discoveryTopic(e entity) string {
+	return fmt.Sprintf("%s/%s/%s/%s/config", b.discoveryPrefix, e.component, b.nodeID, e.objectID)
+}
+
+func (b *Bridge) publishDiscovery() {
+	for _, e := range b.entities() {
+		payload, err := json.Marshal(e.config)
+		if err != nil {
+			continue
+		}
+		b.publish(b.discoveryTopic(e), payload, true)
+	}
+}
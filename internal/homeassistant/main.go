@@ -0,0 +1,344 @@
+// Package homeassistant publishes climate state to MQTT with Home Assistant discovery
+// and maps command topics onto climate methods.
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/ksimuk/ebus-climate/internal/audit"
+	"github.com/ksimuk/ebus-climate/internal/climate"
+	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/ksimuk/ebus-climate/internal/vailant"
+	"github.com/rs/zerolog/log"
+)
+
+const TOPIC_AVAILABILITY = "availability"
+const TOPIC_STATE = "state"
+const TOPIC_MODE_SET = "mode/set"
+const TOPIC_TARGET_SET = "target/set"
+const TOPIC_HW_TARGET_SET = "hw_target/set"
+
+const PAYLOAD_ONLINE = "online"
+const PAYLOAD_OFFLINE = "offline"
+
+const QOS = 1
+const PUBLISH_TIMEOUT = 5 * time.Second
+const COMMAND_BUFFER = 16                   // pending commands from broker
+const FULL_STATE_INTERVAL = 5 * time.Minute // state is republished even without changes
+
+var nodeIDPattern = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// State is payload of state topic
+type State struct {
+	Mode               string  `json:"mode"`   // off, heat
+	Action             string  `json:"action"` // off, idle, heating
+	TargetTemperature  float64 `json:"target_temperature"`
+	InsideTemp         float64 `json:"inside_temp"`
+	OutsideTemp        float64 `json:"outside_temp"`
+	HWTargetTemp       int     `json:"hw_target_temp"`
+	FlowTemp           float64 `json:"flow_temp"`
+	ReturnTemp         float64 `json:"return_temp"`
+	WaterPressure      float64 `json:"water_pressure"`
+	HeatLossBalance    float64 `json:"heat_loss_balance"`
+	HeatLoss           float64 `json:"heat_loss"`
+	GasActive          bool    `json:"gas_active"`
+	EnergyHeating      float64 `json:"energy_heating"`
+	EnergyHotWater     float64 `json:"energy_hot_water"`
+	ConsumptionHeating float64 `json:"consumption_heating"`
+}
+
+type limits struct {
+	MinTargetTemp   float64
+	MaxTargetTemp   float64
+	MinHWTargetTemp int
+	MaxHWTargetTemp int
+}
+
+type Bridge struct {
+	climate         climate.Climate
+	client          mqtt.Client
+	name            string
+	nodeID          string
+	topicPrefix     string
+	discoveryPrefix string
+	interval        time.Duration
+	limits          limits
+	audit           *audit.Log // records commands, optional
+	broker          string
+
+	mu            sync.Mutex
+	lastState     []byte
+	lastPublished time.Time
+
+	commands chan command // handled by run, paho message handlers must not block
+	stopChan chan struct{}
+}
+
+type command struct {
+	topic   string
+	payload string
+}
+
+func newBridge(config *config.Config, c climate.Climate) *Bridge {
+	name := config.Name
+	if name == "" {
+		name = config.MQTT.ClientID
+	}
+	interval := time.Duration(config.MQTT.Interval * float64(time.Second))
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &Bridge{
+		climate:         c,
+		name:            name,
+		nodeID:          nodeIDPattern.ReplaceAllString(config.MQTT.ClientID, "_"),
+		topicPrefix:     strings.TrimSuffix(config.MQTT.TopicPrefix, "/"),
+		discoveryPrefix: strings.TrimSuffix(config.MQTT.DiscoveryPrefix, "/"),
+		interval:        interval,
+		limits:          limits(config.Limits),
+		commands:        make(chan command, COMMAND_BUFFER),
+		stopChan:        make(chan struct{}),
+	}
+}
+
+// Start connects to broker and publishes state, returns nil if MQTT is not configured
+func Start(config *config.Config, c climate.Climate, auditLog *audit.Log) *Bridge {
+	if config.MQTT.Broker == "" {
+		return nil
+	}
+	b := newBridge(config, c)
+	b.audit = auditLog
+	b.broker = config.MQTT.Broker
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(config.MQTT.Broker).
+		SetClientID(config.MQTT.ClientID).
+		SetUsername(config.MQTT.Username).
+		SetPassword(config.MQTT.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetWill(b.topic(TOPIC_AVAILABILITY), PAYLOAD_OFFLINE, QOS, true).
+		SetOnConnectHandler(b.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Warn().Err(err).Msg("MQTT connection lost")
+		})
+	b.client = mqtt.NewClient(opts)
+	log.Info().Msgf("Connecting to MQTT broker %s", config.MQTT.Broker)
+	b.client.Connect() // retried in background until connected
+
+	go b.run()
+	return b
+}
+
+func (b *Bridge) topic(suffix string) string {
+	return b.topicPrefix + "/" + suffix
+}
+
+func (b *Bridge) publish(topic string, payload []byte, retained bool) {
+	if b.client == nil {
+		return
+	}
+	token := b.client.Publish(topic, QOS, retained, payload)
+	if !token.WaitTimeout(PUBLISH_TIMEOUT) {
+		log.Warn().Msgf("Timeout publishing to %s", topic)
+		return
+	}
+	if err := token.Error(); err != nil {
+		log.Error().Err(err).Msgf("Failed to publish to %s", topic)
+	}
+}
+
+// Publish sends payload to topic below topic prefix, not retained
+func (b *Bridge) Publish(suffix string, payload []byte) {
+	b.publish(b.topic(suffix), payload, false)
+}
+
+func (b *Bridge) onConnect(client mqtt.Client) {
+	log.Info().Msg("Connected to MQTT broker")
+	b.publish(b.topic(TOPIC_AVAILABILITY), []byte(PAYLOAD_ONLINE), true)
+	b.publishDiscovery()
+	for _, suffix := range []string{TOPIC_MODE_SET, TOPIC_TARGET_SET, TOPIC_HW_TARGET_SET} {
+		topic := b.topic(suffix)
+		token := client.Subscribe(topic, QOS, func(_ mqtt.Client, msg mqtt.Message) {
+			select {
+			case b.commands <- command{topic: msg.Topic(), payload: string(msg.Payload())}:
+			default:
+				log.Warn().Msgf("Dropping MQTT command on %s, too many pending commands", msg.Topic())
+			}
+		})
+		if token.WaitTimeout(PUBLISH_TIMEOUT) && token.Error() != nil {
+			log.Error().Err(token.Error()).Msgf("Failed to subscribe to %s", topic)
+		}
+	}
+	b.mu.Lock()
+	b.lastState = nil // force state publish after reconnect
+	b.mu.Unlock()
+	b.publishState()
+}
+
+func (b *Bridge) run() {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	events, unsubscribe := b.climate.Events().Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-ticker.C:
+			b.publishState()
+		case <-events:
+			b.publishState()
+		case cmd := <-b.commands:
+			if err := b.handleCommand(cmd.topic, cmd.payload); err != nil {
+				log.Error().Err(err).Msgf("Failed to handle MQTT command on %s", cmd.topic)
+			}
+		case <-b.stopChan:
+			return
+		}
+	}
+}
+
+// state reads current climate state in Home Assistant terms
+func (b *Bridge) state() State {
+	stat := b.climate.GetStat()
+	state := State{
+		Mode:               HA_MODE_OFF,
+		Action:             "off",
+		TargetTemperature:  b.climate.GetTargetTemperature(),
+		InsideTemp:         b.climate.GetInsideTemp(),
+		OutsideTemp:        b.climate.GetOutsideTemp(),
+		HWTargetTemp:       b.climate.GetHWTargetTemp(),
+		FlowTemp:           b.climate.GetFlowTemp(),
+		ReturnTemp:         b.climate.GetReturnTemp(),
+		WaterPressure:      stat.WaterPressure,
+		HeatLossBalance:    b.climate.GetHeatLossBalance(),
+		HeatLoss:           stat.CurrentHeatLoss,
+		GasActive:          b.climate.IsGasActive(),
+		EnergyHeating:      stat.UsageHeating,
+		EnergyHotWater:     stat.UsageHotWater,
+		ConsumptionHeating: b.climate.GetConsumption(),
+	}
+	if b.climate.GetMode() == vailant.MODE_HEATING {
+		state.Mode = HA_MODE_HEAT
+		state.Action = "idle"
+		if state.GasActive {
+			state.Action = "heating"
+		}
+	}
+	return state
+}
+
+// publishState publishes state when it changed or full state interval passed
+func (b *Bridge) publishState() {
+	if b.client == nil || !b.client.IsConnectionOpen() {
+		return
+	}
+	payload, err := json.Marshal(b.state())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode MQTT state")
+		return
+	}
+	b.mu.Lock()
+	changed := string(payload) != string(b.lastState) || time.Since(b.lastPublished) > FULL_STATE_INTERVAL
+	if changed {
+		b.lastState = payload
+		b.lastPublished = time.Now()
+	}
+	b.mu.Unlock()
+	if changed {
+		b.publish(b.topic(TOPIC_STATE), payload, true)
+	}
+}
+
+// handleCommand applies command topic payload to climate
+func (b *Bridge) handleCommand(topic, payload string) error {
+	payload = strings.TrimSpace(payload)
+	log.Info().Msgf("MQTT command %s: %s", topic, payload)
+	var err error
+	var field string // setting changed by command, recorded in audit log
+	var before, after any
+	switch topic {
+	case b.topic(TOPIC_MODE_SET):
+		field, before = "mode", b.climate.GetMode()
+		switch strings.ToLower(payload) {
+		case HA_MODE_HEAT, vailant.MODE_HEATING:
+			after = vailant.MODE_HEATING
+			err = b.climate.SetMode(vailant.MODE_HEATING)
+		case HA_MODE_OFF:
+			after = vailant.MODE_OFF
+			err = b.climate.SetMode(vailant.MODE_OFF)
+		default:
+			field = ""
+			err = fmt.Errorf("unknown mode %q", payload)
+		}
+	case b.topic(TOPIC_TARGET_SET):
+		var temp float64
+		temp, err = strconv.ParseFloat(payload, 64)
+		if err == nil {
+			if temp < b.limits.MinTargetTemp || temp > b.limits.MaxTargetTemp {
+				return fmt.Errorf("target temperature %.1f out of range", temp)
+			}
+			field, before, after = "target_temperature", b.climate.GetTargetTemperature(), temp
+			err = b.climate.SetTargetTemperature(temp)
+		}
+	case b.topic(TOPIC_HW_TARGET_SET):
+		var temp float64
+		temp, err = strconv.ParseFloat(payload, 64)
+		if err == nil {
+			if temp != 0 && (temp < float64(b.limits.MinHWTargetTemp) || temp > float64(b.limits.MaxHWTargetTemp)) {
+				return fmt.Errorf("hot water target temperature %.0f out of range", temp)
+			}
+			field, before, after = "hw_target_temp", b.climate.GetHWTargetTemp(), int(temp)
+			err = b.climate.SetHWTargetTemp(int(temp))
+		}
+	default:
+		err = fmt.Errorf("unknown command topic %s", topic)
+	}
+	if field != "" {
+		b.recordAudit(topic, map[string]any{field: before}, map[string]any{field: after}, err)
+	}
+	if err != nil {
+		return err
+	}
+	b.publishState()
+	return nil
+}
+
+func (b *Bridge) recordAudit(topic string, before, after map[string]any, err error) {
+	if b.audit == nil {
+		return
+	}
+	entry := audit.Entry{
+		Time:      time.Now(),
+		Principal: audit.AUTH_MQTT, // publisher of command is not known to subscriber
+		Auth:      audit.AUTH_MQTT,
+		Source:    b.broker,
+		Endpoint:  topic,
+		Old:       before,
+		New:       after,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if err := b.audit.Append(entry); err != nil {
+		log.Error().Err(err).Msgf("Failed to write audit entry for %s", topic)
+	}
+}
+
+// Stop publishes offline availability and disconnects
+func (b *Bridge) Stop() {
+	close(b.stopChan)
+	if b.client == nil {
+		return
+	}
+	if b.client.IsConnectionOpen() {
+		b.publish(b.topic(TOPIC_AVAILABILITY), []byte(PAYLOAD_OFFLINE), true)
+	}
+	b.client.Disconnect(250)
+}
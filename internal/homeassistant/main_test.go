@@ -0,0 +1,81 @@
+package homeassistant
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ksimuk/ebus-climate/internal/climate"
+	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/ksimuk/ebus-climate/internal/vailant"
+)
+
+// stubClimate records commands, other methods are not used
+type stubClimate struct {
+	climate.Climate
+	mode   string
+	target float64
+	hw     int
+}
+
+func (s *stubClimate) GetMode() string                         { return s.mode }
+func (s *stubClimate) GetTargetTemperature() float64           { return s.target }
+func (s *stubClimate) GetHWTargetTemp() int                    { return s.hw }
+func (s *stubClimate) SetMode(mode string) error               { s.mode = mode; return nil }
+func (s *stubClimate) SetTargetTemperature(temp float64) error { s.target = temp; return nil }
+func (s *stubClimate) SetHWTargetTemp(temp int) error          { s.hw = temp; return nil }
+
+func testBridge(c climate.Climate) *Bridge {
+	cfg := &config.Config{}
+	cfg.MQTT.ClientID = "ebus climate"
+	cfg.MQTT.TopicPrefix = "ebus/"
+	cfg.MQTT.DiscoveryPrefix = "homeassistant"
+	cfg.Limits.MinTargetTemp = 5
+	cfg.Limits.MaxTargetTemp = 30
+	cfg.Limits.MinHWTargetTemp = 35
+	cfg.Limits.MaxHWTargetTemp = 65
+	return newBridge(cfg, c)
+}
+
+func TestHandleCommand(t *testing.T) {
+	c := &stubClimate{}
+	b := testBridge(c)
+
+	if err := b.handleCommand("ebus/mode/set", "heat"); err != nil || c.mode != vailant.MODE_HEATING {
+		t.Errorf("expected heating mode, got %q (%v)", c.mode, err)
+	}
+	if err := b.handleCommand("ebus/mode/set", "off"); err != nil || c.mode != vailant.MODE_OFF {
+		t.Errorf("expected off mode, got %q (%v)", c.mode, err)
+	}
+	if err := b.handleCommand("ebus/mode/set", "cool"); err == nil {
+		t.Error("expected error for unsupported mode")
+	}
+	if err := b.handleCommand("ebus/target/set", "20.5"); err != nil || c.target != 20.5 {
+		t.Errorf("expected target 20.5, got %f (%v)", c.target, err)
+	}
+	if err := b.handleCommand("ebus/target/set", "45"); err == nil || c.target != 20.5 {
+		t.Error("expected out of range target to be rejected")
+	}
+	if err := b.handleCommand("ebus/hw_target/set", "50.0"); err != nil || c.hw != 50 {
+		t.Errorf("expected hot water target 50, got %d (%v)", c.hw, err)
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	b := testBridge(&stubClimate{})
+	entities := b.entities()
+	if len(entities) != 3+len(SENSORS) {
+		t.Fatalf("unexpected number of entities %d", len(entities))
+	}
+	thermostat := entities[0]
+	if topic := b.discoveryTopic(thermostat); topic != "homeassistant/climate/ebus_climate/thermostat/config" {
+		t.Errorf("unexpected discovery topic %s", topic)
+	}
+	if thermostat.config["mode_command_topic"] != "ebus/mode/set" {
+		t.Errorf("unexpected mode command topic %v", thermostat.config["mode_command_topic"])
+	}
+	for _, e := range entities {
+		if !strings.HasPrefix(e.config["unique_id"].(string), "ebus_climate_") {
+			t.Errorf("unexpected unique id %v", e.config["unique_id"])
+		}
+	}
+}
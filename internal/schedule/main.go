@@ -0,0 +1,157 @@
+// Package schedule resolves weekly switch points ("mon-fri at 06:30") against wall clock time.
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Point is a weekly switch point, Index refers to the caller's list of values
+type Point struct {
+	Index  int
+	Days   []time.Weekday // empty means every day
+	Minute int            // minutes since midnight
+}
+
+type Schedule struct {
+	points []Point
+}
+
+// Occurrence is a point resolved to a concrete time
+type Occurrence struct {
+	Index int
+	At    time.Time
+}
+
+// ParseTime parses "HH:MM" into minutes since midnight
+func ParseTime(value string) (int, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil || hours < 0 || hours > 23 {
+		return 0, fmt.Errorf("invalid hours in %q", value)
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil || minutes < 0 || minutes > 59 {
+		return 0, fmt.Errorf("invalid minutes in %q", value)
+	}
+	return hours*60 + minutes, nil
+}
+
+// ParseDays parses day names (mon, tue, ...) and ranges (mon-fri)
+func ParseDays(days []string) ([]time.Weekday, error) {
+	result := []time.Weekday{}
+	for _, day := range days {
+		day = strings.ToLower(strings.TrimSpace(day))
+		if from, to, ok := strings.Cut(day, "-"); ok {
+			start, ok1 := weekdays[from]
+			end, ok2 := weekdays[to]
+			if !ok1 || !ok2 {
+				return nil, fmt.Errorf("invalid day range %q", day)
+			}
+			for d := start; ; d = (d + 1) % 7 {
+				result = append(result, d)
+				if d == end {
+					break
+				}
+			}
+			continue
+		}
+		weekday, ok := weekdays[day]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", day)
+		}
+		result = append(result, weekday)
+	}
+	return result, nil
+}
+
+// NewPoint builds a point from config values
+func NewPoint(index int, days []string, at string) (Point, error) {
+	minute, err := ParseTime(at)
+	if err != nil {
+		return Point{}, err
+	}
+	weekdays, err := ParseDays(days)
+	if err != nil {
+		return Point{}, err
+	}
+	return Point{Index: index, Days: weekdays, Minute: minute}, nil
+}
+
+func New(points []Point) *Schedule {
+	sorted := append([]Point{}, points...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Minute < sorted[j].Minute })
+	return &Schedule{points: sorted}
+}
+
+func (s *Schedule) IsEmpty() bool {
+	return s == nil || len(s.points) == 0
+}
+
+func (p Point) appliesOn(day time.Weekday) bool {
+	if len(p.Days) == 0 {
+		return true
+	}
+	for _, d := range p.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Point) on(day time.Time) time.Time {
+	y, m, d := day.Date()
+	return time.Date(y, m, d, p.Minute/60, p.Minute%60, 0, 0, day.Location())
+}
+
+// Active returns the last point that started at or before t, looking back up to a week
+func (s *Schedule) Active(t time.Time) (Occurrence, bool) {
+	if s.IsEmpty() {
+		return Occurrence{}, false
+	}
+	for offset := 0; offset <= 7; offset++ {
+		day := t.AddDate(0, 0, -offset)
+		for i := len(s.points) - 1; i >= 0; i-- {
+			p := s.points[i]
+			at := p.on(day)
+			if p.appliesOn(day.Weekday()) && !at.After(t) {
+				return Occurrence{Index: p.Index, At: at}, true
+			}
+		}
+	}
+	return Occurrence{}, false
+}
+
+// Next returns the first point starting strictly after t, looking ahead up to a week
+func (s *Schedule) Next(t time.Time) (Occurrence, bool) {
+	if s.IsEmpty() {
+		return Occurrence{}, false
+	}
+	for offset := 0; offset <= 7; offset++ {
+		day := t.AddDate(0, 0, offset)
+		for _, p := range s.points {
+			at := p.on(day)
+			if p.appliesOn(day.Weekday()) && at.After(t) {
+				return Occurrence{Index: p.Index, At: at}, true
+			}
+		}
+	}
+	return Occurrence{}, false
+}
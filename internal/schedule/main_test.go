@@ -0,0 +1,91 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func mustPoint(t *testing.T, index int, days []string, at string) Point {
+	p, err := NewPoint(index, days, at)
+	if err != nil {
+		t.Fatalf("NewPoint(%v, %q): %v", days, at, err)
+	}
+	return p
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int
+		valid    bool
+	}{
+		{"06:30", 390, true},
+		{"0:00", 0, true},
+		{"23:59", 1439, true},
+		{"24:00", 0, false},
+		{"12:60", 0, false},
+		{"1230", 0, false},
+	}
+	for _, tt := range tests {
+		minute, err := ParseTime(tt.value)
+		if (err == nil) != tt.valid {
+			t.Errorf("ParseTime(%q) error = %v, expected valid %v", tt.value, err, tt.valid)
+			continue
+		}
+		if tt.valid && minute != tt.expected {
+			t.Errorf("ParseTime(%q) = %d, expected %d", tt.value, minute, tt.expected)
+		}
+	}
+}
+
+func TestParseDaysRange(t *testing.T) {
+	days, err := ParseDays([]string{"fri-mon"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []time.Weekday{time.Friday, time.Saturday, time.Sunday, time.Monday}
+	if len(days) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, days)
+	}
+	for i := range expected {
+		if days[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, days)
+		}
+	}
+}
+
+func TestActiveAndNext(t *testing.T) {
+	s := New([]Point{
+		mustPoint(t, 0, nil, "22:00"),
+		mustPoint(t, 1, []string{"mon-fri"}, "06:30"),
+		mustPoint(t, 2, []string{"sat", "sun"}, "08:00"),
+	})
+
+	// Monday 2024-01-01 05:00, previous point is Sunday 22:00
+	now := time.Date(2024, 1, 1, 5, 0, 0, 0, time.UTC)
+	active, ok := s.Active(now)
+	if !ok || active.Index != 0 || !active.At.Equal(time.Date(2023, 12, 31, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected active point %+v", active)
+	}
+	next, ok := s.Next(now)
+	if !ok || next.Index != 1 || !next.At.Equal(time.Date(2024, 1, 1, 6, 30, 0, 0, time.UTC)) {
+		t.Errorf("unexpected next point %+v", next)
+	}
+
+	// Saturday 2024-01-06 07:00, weekday morning point does not apply
+	now = time.Date(2024, 1, 6, 7, 0, 0, 0, time.UTC)
+	next, ok = s.Next(now)
+	if !ok || next.Index != 2 || !next.At.Equal(time.Date(2024, 1, 6, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected next point %+v", next)
+	}
+}
+
+func TestEmptySchedule(t *testing.T) {
+	s := New(nil)
+	if _, ok := s.Active(time.Now()); ok {
+		t.Error("expected no active point for empty schedule")
+	}
+	if _, ok := s.Next(time.Now()); ok {
+		t.Error("expected no next point for empty schedule")
+	}
+}
@@ -0,0 +1,82 @@
+// Cycle log records every burn with its trigger, planned and actual length
+
+package vailant
+
+import (
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/climate"
+	"github.com/rs/zerolog/log"
+)
+
+func (c *eBusClimate) beginCycle(trigger string, plannedMinutes float64) {
+	c.cycleMutex.Lock()
+	defer c.cycleMutex.Unlock()
+	c.cycle = &climate.Cycle{
+		Start:            time.Now(),
+		Trigger:          trigger,
+		PlannedMinutes:   plannedMinutes,
+		InsideTempStart:  c.state.InsideTemp,
+		OutsideTempStart: c.state.OutsideTemp,
+		TargetTemp:       c.targetTemperature(),
+		HeatLossBalance:  c.state.HeatLoss,
+		EnergyBoiler:     c.stat.UsageHeating, // counter at start, replaced by delta at end
+	}
+}
+
+// extendCycle records extension of running cycle by further request
+func (c *eBusClimate) extendCycle(minutes float64) {
+	c.cycleMutex.Lock()
+	defer c.cycleMutex.Unlock()
+	if c.cycle == nil {
+		return
+	}
+	c.cycle.PlannedMinutes += minutes
+	c.cycle.Extensions++
+}
+
+// extendCycleHwc records one minute extension because of hot water demand
+func (c *eBusClimate) extendCycleHwc() {
+	c.cycleMutex.Lock()
+	defer c.cycleMutex.Unlock()
+	if c.cycle == nil {
+		return
+	}
+	c.cycle.HwcExtensions++
+}
+
+func (c *eBusClimate) endCycle() {
+	c.cycleMutex.Lock()
+	cycle := c.cycle
+	c.cycle = nil
+	c.cycleMutex.Unlock()
+	if cycle == nil {
+		return
+	}
+
+	cycle.End = time.Now()
+	cycle.ActualMinutes = cycle.End.Sub(cycle.Start).Minutes()
+	cycle.InsideTempEnd = c.state.InsideTemp
+	cycle.OutsideTempEnd = c.state.OutsideTemp
+	cycle.EnergyEstimated = float64(c.power) * cycle.ActualMinutes / 60 / 1000
+	if cycle.EnergyBoiler >= 0 && c.stat.UsageHeating >= 0 {
+		cycle.EnergyBoiler = c.stat.UsageHeating - cycle.EnergyBoiler
+	} else {
+		cycle.EnergyBoiler = -1
+	}
+	log.Info().Msgf("Heating cycle (%s) finished after %.1f minutes, planned %.0f", cycle.Trigger, cycle.ActualMinutes, cycle.PlannedMinutes)
+
+	if c.cycleLog == nil {
+		return
+	}
+	if err := c.cycleLog.Append(*cycle); err != nil {
+		log.Error().Err(err).Msg("Failed to record heating cycle")
+	}
+}
+
+func (c *eBusClimate) GetCycles(from, to time.Time) ([]climate.Cycle, error) {
+	if c.cycleLog == nil {
+		return []climate.Cycle{}, nil
+	}
+	return c.cycleLog.Query(from, to)
+}
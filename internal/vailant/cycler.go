@@ -4,6 +4,8 @@ package vailant
 import (
 	"time"
 
+	"github.com/ksimuk/ebus-climate/internal/climate"
+
 	"github.com/rs/zerolog/log"
 )
 
@@ -20,16 +22,26 @@ func (c *eBusClimate) isHwcDemandActive() bool {
 }
 
 func (c *eBusClimate) startCycler() {
+	c.cyclerHeartbeat.Store(time.Now().UnixNano())
+	c.updateSchedule(time.Now())
 	c.calculateLoss() // initial calculation
+	c.updateHotWater(time.Now())
 	// launch cycler goroutine every minute
+	c.workers.Add(1)
 	go func() {
+		defer c.workers.Done()
 		ticker := time.NewTicker(time.Minute * CYCLE_CHECK_INTERVAL)
 		defer ticker.Stop()
 		for {
 			select {
 			case <-ticker.C:
+				c.cyclerHeartbeat.Store(time.Now().UnixNano())
 				c.calculateConsumption()
+				c.applyForecast(time.Now())
+				c.refreshTemperatures(time.Now())
+				c.updateSchedule(time.Now())
 				c.calculateLoss()
+				c.updateHotWater(time.Now())
 				c.pingHeating() // keep connection with boiler active
 
 			case <-c.stopChan:
@@ -44,6 +56,7 @@ func (c *eBusClimate) calculateConsumption() {
 		c.state.ConsumptionHeating += float64(c.power) * CYCLE_CHECK_INTERVAL / 60 / 1000 // per minute kWh
 	}
 	c.stateStore.Save(c.state) // save state with new consumption and heat loss
+	c.recordEnergy(time.Now())
 
 }
 
@@ -52,27 +65,57 @@ func (c *eBusClimate) calculateConsumption() {
 // negative means we are above target, positive means we are below target
 func (c *eBusClimate) adjustTemp() float64 {
 	insideTemp := c.state.InsideTemp
-	targetTemp := c.state.TargetTemperature
+	targetTemp := c.targetTemperature()
 
 	adjustment := targetTemp - insideTemp
 
 	return adjustment * c.adjustmentRate
 }
 
-func (c *eBusClimate) getMinuteLoss() float64 {
+// lossTerms is heat loss in W split by contribution
+type lossTerms struct {
+	base  float64 // transmission loss by outside temperature
+	wind  float64 // extra infiltration loss by wind
+	solar float64 // solar gain, reduces loss
+}
+
+func (t lossTerms) total() float64 {
+	return t.base + t.wind - t.solar
+}
+
+func (c *eBusClimate) getLossTerms() lossTerms {
+	if c.degradedMode == DEGRADED_FROST_PROTECTION {
+		return lossTerms{base: c.frostProtectionLoss() * 60}
+	}
+
 	// TODO adjust based on inside target temp
-	current_weather := c.state.OutsideTemp
+	current_weather := c.planningOutsideTemp()
 
 	// reduce loss if target temp is lower than base temperature (we are in setback)
-	current_weather += BASE_TEMP - c.state.TargetTemperature
-	// adjust loss based on how far we are from target temp
-	current_weather -= c.adjustTemp()
+	current_weather += BASE_TEMP - c.targetTemperature()
+	// adjust loss based on how far we are from target temp, unknown when inside temp is stale
+	if c.degradedMode == DEGRADED_NONE || c.degradedMode == DEGRADED_INSIDE_ONLY {
+		current_weather -= c.adjustTemp()
+	}
 
 	loss3 := c.loss3
 	loss7 := c.loss7
 
-	currentLossW := float64(loss3-loss7)/10*(7-current_weather) + float64(loss7)
-	return currentLossW / 60 // per minute
+	terms := lossTerms{
+		base: float64(loss3-loss7)/10*(7-current_weather) + float64(loss7),
+	}
+	now := time.Now()
+	if speed, _, _, ok := c.windSpeed.Current(now); ok && terms.base > 0 {
+		terms.wind = terms.base * c.windLossFactor * speed
+	}
+	if irradiance, _, _, ok := c.irradiance.Current(now); ok {
+		terms.solar = c.solarGainFactor * irradiance
+	}
+	return terms
+}
+
+func (c *eBusClimate) getMinuteLoss() float64 {
+	return c.getLossTerms().total() / 60 // per minute
 }
 
 func (c *eBusClimate) calculateLoss() {
@@ -81,8 +124,12 @@ func (c *eBusClimate) calculateLoss() {
 		return
 	}
 
-	currentLoss := c.getMinuteLoss()
+	terms := c.getLossTerms()
+	currentLoss := terms.total() / 60
 	c.stat.CurrentHeatLoss = currentLoss * 60 // in W
+	c.stat.LossBase = terms.base
+	c.stat.LossWind = terms.wind
+	c.stat.LossSolar = -terms.solar
 	if !(currentLoss < 0 && c.state.HeatLoss > 3000) {
 		c.state.HeatLoss = c.state.HeatLoss - currentLoss
 	}
@@ -94,6 +141,11 @@ func (c *eBusClimate) calculateLoss() {
 		c.state.HeatLoss = c.state.HeatLoss + c.runCycle()
 		log.Info().Msgf("Starting new heating cycle to cover heat loss, new balance %f", c.state.HeatLoss)
 	}
+	c.events.Publish(climate.EVENT_LOSS, map[string]any{
+		"heat_loss_balance": c.state.HeatLoss,
+		"current_heat_loss": c.stat.CurrentHeatLoss,
+		"runtime":           c.stat.Runtime,
+	})
 }
 
 func (c *eBusClimate) getRuntime() int {
@@ -124,36 +176,53 @@ func (c *eBusClimate) runCycle() float64 {
 }
 
 func (c *eBusClimate) RunFor(minutes int) {
-	c.runFor(minutes)
+	c.runForTrigger(minutes, climate.TRIGGER_MANUAL)
 }
 
 func (c *eBusClimate) runFor(minutes int) {
+	c.runForTrigger(minutes, climate.TRIGGER_MODEL)
+}
+
+func (c *eBusClimate) runForTrigger(minutes int, trigger string) {
 	<-c.heatingTimerMutex                                // acquire lock
 	defer func() { c.heatingTimerMutex <- struct{}{} }() // release lock
 
 	if c.heatingActive {
 		// Heating is already active, add minutes to existing cycle
+		c.extendCycle(float64(minutes))
 		c.heatingEndTime = c.heatingEndTime.Add(time.Duration(minutes) * time.Minute)
 		log.Info().Msgf("Extending heating cycle by %d minutes, new end time: %s", minutes, c.heatingEndTime.Format("15:04:05"))
 		return
 	}
 
+	if c.stopped() {
+		return // cycle would outlive Shutdown
+	}
+
 	// Start new heating cycle
 	c.heatingEndTime = time.Now().Add(time.Duration(minutes) * time.Minute)
 	log.Info().Msgf("Start heating cycle for %d minutes (until %s)", minutes, c.heatingEndTime.Format("15:04:05"))
 
+	c.workers.Add(1)
 	go func() {
-		c.StartHeating()
+		defer c.workers.Done()
+		c.startHeating(trigger, float64(minutes))
 		interval := time.Minute
 		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 
-		for range ticker.C {
+		for {
+			select {
+			case <-ticker.C:
+			case <-c.stopChan:
+				return // running cycle is ended by Shutdown
+			}
 			// Check if HwcDemand is active and extend by 1 minute
 			if c.isHwcDemandActive() {
 				<-c.heatingTimerMutex
 				c.heatingEndTime = c.heatingEndTime.Add(interval)
 				c.heatingTimerMutex <- struct{}{}
+				c.extendCycleHwc()
 				log.Info().Msgf("HwcDemand active during heating - extending cycle by 1 minute (until %s)", c.heatingEndTime.Format("15:04:05"))
 			}
 
@@ -29,8 +29,20 @@ func (m *mockPin) Out(l gpio.Level) error {
 }
 func (m *mockPin) PWM(duty gpio.Duty, freq physic.Frequency) error { return nil }
 
+type memoryStore struct {
+	state climate.ClimateState
+}
+
+func (m *memoryStore) Load() (*climate.ClimateState, error) { return &m.state, nil }
+func (m *memoryStore) Save(state *climate.ClimateState) error {
+	m.state = *state
+	return nil
+}
+func (m *memoryStore) SaveNow(state *climate.ClimateState) error { return m.Save(state) }
+
 func createTestClimate() *eBusClimate {
 	c := &eBusClimate{
+		stateStore:        &memoryStore{},
 		stopChan:          make(chan struct{}),
 		heatingActive:     false,
 		heatingRelay:      &mockPin{},
@@ -41,7 +53,12 @@ func createTestClimate() *eBusClimate {
 		state: &climate.ClimateState{
 			Mode: MODE_HEATING,
 		},
-		power: 1000,
+		power:   1000,
+		inside:  climate.NewInput(nil),
+		outside: climate.NewInput(nil),
+
+		windSpeed:  climate.NewInput(nil),
+		irradiance: climate.NewInput(nil),
 	}
 	c.heatingTimerMutex <- struct{}{} // initialize mutex
 	return c
@@ -209,3 +226,48 @@ func TestConcurrentRunForCalls(t *testing.T) {
 	c.StopHeating()
 	close(c.stopChan)
 }
+
+func TestLossTermsSolarAndWind(t *testing.T) {
+	c := createScheduledClimate(t)
+	c.state.TargetTemperature = 20
+	c.state.InsideTemp = 20
+	c.windLossFactor = 0.05
+	c.solarGainFactor = 4
+
+	base := c.getLossTerms()
+	if base.wind != 0 || base.solar != 0 || base.base != 1300 {
+		t.Fatalf("Expected only base loss 1300W, got %+v", base)
+	}
+
+	c.SetWindSpeed("http", 4)
+	c.SetIrradiance("http", 200)
+	terms := c.getLossTerms()
+	if terms.wind != 260 || terms.solar != 800 {
+		t.Errorf("Expected wind 260W and solar 800W, got %+v", terms)
+	}
+	if terms.total() != 1300+260-800 {
+		t.Errorf("Unexpected total loss %f", terms.total())
+	}
+}
+
+func TestShutdownEndsCycle(t *testing.T) {
+	c := createTestClimate()
+	c.runFor(30)
+	time.Sleep(100 * time.Millisecond)
+	if !c.heatingActive {
+		t.Fatal("Expected heating to be active")
+	}
+
+	c.Shutdown()
+	if c.heatingActive || c.heatingRelay.(*mockPin).level != gpio.Low {
+		t.Error("Expected heating to be stopped with relay low")
+	}
+
+	// no new cycle once shut down
+	c.runFor(5)
+	c.startHeating(climate.TRIGGER_MANUAL, 0)
+	time.Sleep(100 * time.Millisecond)
+	if c.heatingActive || c.heatingRelay.(*mockPin).level != gpio.Low {
+		t.Error("Expected heating to stay off after shutdown")
+	}
+}
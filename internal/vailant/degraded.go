@@ -0,0 +1,64 @@
+// Degraded mode keeps the house heated when temperature inputs go stale:
+// without inside temperature cycling follows outside temperature heat loss only,
+// without outside temperature heat loss is planned from forecast or design temperature
+// and corrected by inside temperature error, without both temperatures boiler runs
+// a fixed frost protection duty cycle.
+package vailant
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const DEGRADED_NONE = ""
+const DEGRADED_OUTSIDE_ONLY = "outside_only"         // inside temperature is stale
+const DEGRADED_INSIDE_ONLY = "inside_only"           // outside temperature is stale
+const DEGRADED_FROST_PROTECTION = "frost_protection" // inside and outside temperatures are stale
+
+func (c *eBusClimate) updateDegradedMode() {
+	mode := DEGRADED_NONE
+	switch {
+	case c.insideSource == "" && c.outsideSource == "":
+		mode = DEGRADED_FROST_PROTECTION
+	case c.insideSource == "":
+		mode = DEGRADED_OUTSIDE_ONLY
+	case c.outsideSource == "":
+		mode = DEGRADED_INSIDE_ONLY
+	}
+	if mode == c.degradedMode {
+		return
+	}
+	switch mode {
+	case DEGRADED_NONE:
+		log.Info().Msg("Temperature inputs recovered, leaving degraded mode")
+	case DEGRADED_OUTSIDE_ONLY:
+		log.Warn().Msg("Inside temperature is stale, heating by outside temperature heat loss only")
+	case DEGRADED_INSIDE_ONLY:
+		log.Warn().Msgf("Outside temperature is stale, planning heat loss from forecast or %.1f°C design temperature", c.designTemp)
+	case DEGRADED_FROST_PROTECTION:
+		log.Warn().Msgf("Inside and outside temperatures are stale, running frost protection at %.0f%% duty", c.frostDutyCycle*100)
+	}
+	c.degradedMode = mode
+	c.stat.DegradedMode = mode
+}
+
+// staleOutsideTemp returns outside temperature assumed while outside readings are stale
+func (c *eBusClimate) staleOutsideTemp() float64 {
+	if c.forecastAheadValid {
+		return c.forecastAhead
+	}
+	return c.designTemp
+}
+
+// frostProtectionLoss returns per minute loss which makes the cycler run configured duty cycle
+func (c *eBusClimate) frostProtectionLoss() float64 {
+	return float64(c.power) * c.frostDutyCycle / 60
+}
+
+func (c *eBusClimate) boilerStat() string {
+	if c.boilerUpdated.IsZero() {
+		return ""
+	}
+	return c.boilerUpdated.Format(time.RFC3339)
+}
@@ -0,0 +1,104 @@
+package vailant
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/climate"
+)
+
+func createDegradedClimate() *eBusClimate {
+	c := createTestClimate()
+	c.loss3 = 5000
+	c.loss7 = 3000
+	c.adjustmentRate = 3
+	c.frostDutyCycle = 0.1
+	c.designTemp = -3
+	c.state.TargetTemperature = 20
+	return c
+}
+
+// setTemperatures records readings which are fresh or expired by now
+func setTemperatures(c *eBusClimate, insideFresh, outsideFresh bool) {
+	now := time.Now()
+	stale := now.Add(-time.Hour)
+	insideTime, outsideTime := now, now
+	if !insideFresh {
+		insideTime = stale
+	}
+	if !outsideFresh {
+		outsideTime = stale
+	}
+	c.inside.Update(climate.SOURCE_HTTP, 19, 0, insideTime)
+	c.outside.Update(climate.SOURCE_HTTP, 7, 0, outsideTime)
+	c.refreshTemperatures(now)
+}
+
+func TestDegradedModes(t *testing.T) {
+	tests := []struct {
+		name         string
+		insideFresh  bool
+		outsideFresh bool
+		mode         string
+		loss         float64 // W
+	}{
+		// outside 7 corrected by 1 degree below target
+		{"fresh", true, true, DEGRADED_NONE, 3600},
+		// outside 7 without correction
+		{"inside stale", false, true, DEGRADED_OUTSIDE_ONLY, 3000},
+		// design temperature corrected by 1 degree below target
+		{"outside stale", true, false, DEGRADED_INSIDE_ONLY, 5600},
+		// fixed duty cycle of 1000 W boiler
+		{"both stale", false, false, DEGRADED_FROST_PROTECTION, 100},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := createDegradedClimate()
+			setTemperatures(c, test.insideFresh, test.outsideFresh)
+			if c.degradedMode != test.mode || c.stat.DegradedMode != test.mode {
+				t.Errorf("expected mode %q, got %q", test.mode, c.degradedMode)
+			}
+			if loss := c.getLossTerms().total(); math.Abs(loss-test.loss) > 1e-9 {
+				t.Errorf("expected loss %.0f W, got %.2f", test.loss, loss)
+			}
+		})
+	}
+}
+
+func TestStaleOutsideUsesForecast(t *testing.T) {
+	c := createDegradedClimate()
+	c.forecastAhead = 2
+	c.forecastAheadValid = true
+	setTemperatures(c, true, false)
+	// forecast 2 corrected by 1 degree below target
+	if loss := c.getLossTerms().total(); math.Abs(loss-4600) > 1e-9 {
+		t.Errorf("expected loss from forecast 4600 W, got %.2f", loss)
+	}
+
+	// fresh readings leave degraded mode
+	setTemperatures(c, true, true)
+	if c.degradedMode != DEGRADED_NONE {
+		t.Errorf("expected recovery, got %q", c.degradedMode)
+	}
+}
+
+func TestFrostProtectionDutyCycle(t *testing.T) {
+	c := createDegradedClimate()
+	setTemperatures(c, false, false)
+
+	// an hour of minute losses is duty cycle of boiler power
+	total := 0.0
+	for i := 0; i < 60; i++ {
+		total += c.getMinuteLoss()
+	}
+	if expected := float64(c.power) * c.frostDutyCycle; math.Abs(total-expected) > 1e-9 {
+		t.Errorf("expected %.0f Wh per hour, got %.2f", expected, total)
+	}
+
+	// duty cycle does not depend on target
+	c.state.TargetTemperature = 25
+	if loss := c.frostProtectionLoss(); loss != float64(c.power)*0.1/60 {
+		t.Errorf("unexpected frost protection loss %f", loss)
+	}
+}
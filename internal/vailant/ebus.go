@@ -3,6 +3,9 @@ package vailant
 import (
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/climate"
 )
 
 func (c *eBusClimate) onChange(newValues map[string]string) {
@@ -21,28 +24,73 @@ func (c *eBusClimate) onChange(newValues map[string]string) {
 			c.modulationTemp = int(getFloat(value))
 		case "PrEnergySumHwc1":
 			// parse float
-			c.stat.UsageHotWater = getFloat(value) / PER_KWH_ADJUSTMENT
+			c.hotWaterCounter = getFloat(value)
+			c.stat.UsageHotWater = c.hotWaterCounter / PER_KWH_ADJUSTMENT
 		case "PrEnergySumHc1":
 			// parse float
-			c.stat.UsageHeating = getFloat(value) / PER_KWH_ADJUSTMENT
+			c.heatingCounter = getFloat(value)
+			c.stat.UsageHeating = c.heatingCounter / PER_KWH_ADJUSTMENT
 		case "WaterPressure":
 			// parse float
 			c.stat.WaterPressure = getFloat(value)
 		case "HwcDemand":
 			c.stat.HwcDemand = value
-
+		case c.hotWater.storageTempMessage:
+			c.hotWater.storageTemp = getFloat(value)
 		}
 	}
+	if len(newValues) > 0 {
+		c.boilerUpdated = time.Now()
+	}
+	c.publishBoilerChanges(newValues)
 	c.onReturnTemperatureChange()
 }
 
 func getFloat(values string) float64 {
+	value, _ := parseFloat(values)
+	return value
+}
+
+func parseFloat(values string) (float64, bool) {
 	// 32.94;65008;ok
 	parts := strings.Split(values, ";")
 	if len(parts) > 0 {
 		if value, err := strconv.ParseFloat(parts[0], 64); err == nil {
-			return value
+			return value, true
+		}
+	}
+	return 0, false
+}
+
+// publishBoilerChanges publishes values which differ from previous read
+func (c *eBusClimate) publishBoilerChanges(newValues map[string]string) {
+	if c.boilerState == nil {
+		c.boilerState = map[string]string{}
+	}
+	changes := map[string]any{}
+	for key, value := range newValues {
+		if previous, ok := c.boilerState[key]; ok && previous == value {
+			continue
+		}
+		c.boilerState[key] = value
+		if number, ok := parseFloat(value); ok {
+			changes[key] = number
+		} else {
+			changes[key] = value
+		}
+	}
+	c.events.Publish(climate.EVENT_BOILER, changes)
+}
+
+// parseBoilerError returns error codes of error history value like "-;-;-;-;-", empty if none
+func parseBoilerError(value string) string {
+	codes := []string{}
+	for _, part := range strings.Split(value, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" || part == "-" || part == "0" || part == "ok" {
+			continue
 		}
+		codes = append(codes, part)
 	}
-	return 0
+	return strings.Join(codes, ",")
 }
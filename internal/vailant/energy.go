@@ -0,0 +1,44 @@
+// Energy ledger is fed every minute with burn state and boiler counters
+
+package vailant
+
+import (
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/energy"
+)
+
+func (c *eBusClimate) recordEnergy(now time.Time) {
+	if c.energy == nil {
+		return
+	}
+	c.energy.Record(energy.Sample{
+		Time:        now,
+		HeatingRaw:  c.heatingCounter,
+		HotWaterRaw: c.hotWaterCounter,
+		Burning:     c.IsGasActive(),
+		OutsideTemp: c.state.OutsideTemp,
+		OutsideOK:   c.outsideSource != "",
+		Power:       float64(c.power),
+		Interval:    time.Minute * CYCLE_CHECK_INTERVAL,
+	})
+	if day, ok := c.energy.LastDegreeDay(); ok {
+		c.stat.KWhPerDegreeDay = day.KWhPerDegreeDay
+		c.stat.EfficiencyBaseline = day.Baseline
+		c.stat.EfficiencyRegression = day.Regression
+	}
+}
+
+func (c *eBusClimate) GetEnergy(period string, from, to time.Time) energy.Report {
+	if c.energy == nil {
+		return energy.Report{Period: period, Factor: PER_KWH_ADJUSTMENT, Entries: []energy.Entry{}}
+	}
+	return c.energy.Report(period, from, to)
+}
+
+func (c *eBusClimate) GetDegreeDays(period string, from, to time.Time) energy.DegreeDayReport {
+	if c.energy == nil {
+		return energy.DegreeDayReport{Period: period, Entries: []energy.DegreeDay{}}
+	}
+	return c.energy.DegreeDays(period, from, to)
+}
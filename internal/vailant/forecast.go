@@ -0,0 +1,91 @@
+// Forecast fills in outside temperature when sensors are missing
+// and lets heat loss model anticipate cold fronts over the next few hours.
+
+package vailant
+
+import (
+	"context"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/climate"
+	"github.com/ksimuk/ebus-climate/internal/weather"
+	"github.com/rs/zerolog/log"
+)
+
+func (c *eBusClimate) startForecast(provider weather.Provider, refresh time.Duration) {
+	if provider == nil {
+		return
+	}
+	if refresh <= 0 {
+		refresh = time.Hour
+	}
+	log.Debug().Msgf("Start forecast updates every %v", refresh)
+	c.workers.Add(1)
+	go func() {
+		defer c.workers.Done()
+		c.updateForecast(provider)
+		ticker := time.NewTicker(refresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.updateForecast(provider)
+			case <-c.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+func (c *eBusClimate) updateForecast(provider weather.Provider) {
+	ctx, cancel := context.WithTimeout(context.Background(), weather.REQUEST_TIMEOUT)
+	defer cancel()
+	forecast, err := provider.Forecast(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to update weather forecast")
+		return
+	}
+	log.Debug().Msgf("Updated weather forecast with %d points", len(forecast))
+	c.forecastMutex.Lock()
+	c.forecast = forecast
+	c.forecastMutex.Unlock()
+}
+
+func (c *eBusClimate) getForecast() weather.Forecast {
+	c.forecastMutex.Lock()
+	defer c.forecastMutex.Unlock()
+	return c.forecast
+}
+
+// applyForecast feeds forecast as low priority outside temperature source and updates lookahead temperature
+func (c *eBusClimate) applyForecast(now time.Time) {
+	forecast := c.getForecast()
+	if p, ok := forecast.At(now); ok {
+		c.outside.Update(climate.SOURCE_FORECAST, p.Temp, 0, now)
+		c.windSpeed.Update(climate.SOURCE_FORECAST, p.WindSpeed, 0, now)
+		c.irradiance.Update(climate.SOURCE_FORECAST, p.Irradiance, 0, now)
+	}
+
+	c.forecastAheadValid = false
+	c.stat.ForecastTemp = 0
+	if c.forecastLookahead <= 0 {
+		return
+	}
+	if avg, ok := forecast.Average(now, now.Add(c.forecastLookahead)); ok {
+		c.forecastAhead = avg.Temp
+		c.forecastAheadValid = true
+		c.stat.ForecastTemp = avg.Temp
+	}
+}
+
+// planningOutsideTemp returns outside temperature used to plan cycles,
+// colder forecast for the next hours is used ahead of time
+func (c *eBusClimate) planningOutsideTemp() float64 {
+	if c.degradedMode == DEGRADED_INSIDE_ONLY {
+		return c.staleOutsideTemp()
+	}
+	if c.forecastAheadValid && c.forecastAhead < c.state.OutsideTemp {
+		return c.forecastAhead
+	}
+	return c.state.OutsideTemp
+}
@@ -0,0 +1,120 @@
+package vailant
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/climate"
+)
+
+const BOILER_STALE = 3 * POOLING_INTERVAL   // degraded without boiler reads
+const BOILER_FAILED = 10 * POOLING_INTERVAL // failed without boiler reads
+const CYCLER_FAILED = 3 * time.Minute * CYCLE_CHECK_INTERVAL
+
+func component(name string, updated time.Time, now time.Time) climate.ComponentHealth {
+	h := climate.ComponentHealth{Name: name, Status: climate.HEALTH_OK}
+	if !updated.IsZero() {
+		h.Updated = updated.Format(time.RFC3339)
+		h.AgeSeconds = now.Sub(updated).Seconds()
+	}
+	return h
+}
+
+// Health reports whether boiler bus, sensors, state store, relay and cycler work
+func (c *eBusClimate) Health() climate.Health {
+	now := time.Now()
+	return climate.Health{Components: []climate.ComponentHealth{
+		c.boilerHealth(now),
+		c.insideHealth(now),
+		c.outsideHealth(now),
+		c.storeHealth(now),
+		c.relayHealth(now),
+		c.cyclerHealth(now),
+	}}
+}
+
+func (c *eBusClimate) boilerHealth(now time.Time) climate.ComponentHealth {
+	h := component(climate.COMPONENT_EBUSD, c.boilerUpdated, now)
+	age := now.Sub(c.boilerUpdated)
+	switch {
+	case c.boilerUpdated.IsZero():
+		h.Status = climate.HEALTH_FAILED
+		h.Message = "no successful read from ebusd"
+	case age > BOILER_FAILED:
+		h.Status = climate.HEALTH_FAILED
+		h.Message = fmt.Sprintf("no read from ebusd for %s", age.Round(time.Second))
+	case age > BOILER_STALE:
+		h.Status = climate.HEALTH_DEGRADED
+		h.Message = fmt.Sprintf("no read from ebusd for %s", age.Round(time.Second))
+	}
+	return h
+}
+
+func (c *eBusClimate) sensorHealth(name string, input *climate.Input, source string, now time.Time) climate.ComponentHealth {
+	_, _, updated, _ := input.Current(now)
+	h := component(name, updated, now)
+	if source == "" {
+		h.Status = climate.HEALTH_DEGRADED
+		h.Message = "no fresh temperature reading"
+	} else {
+		h.Message = "using " + source
+	}
+	return h
+}
+
+func (c *eBusClimate) insideHealth(now time.Time) climate.ComponentHealth {
+	return c.sensorHealth(climate.COMPONENT_INSIDE_SENSOR, c.inside, c.insideSource, now)
+}
+
+// outsideHealth fails when no temperature is known at all and only frost protection runs
+func (c *eBusClimate) outsideHealth(now time.Time) climate.ComponentHealth {
+	h := c.sensorHealth(climate.COMPONENT_OUTSIDE_SENSOR, c.outside, c.outsideSource, now)
+	if c.degradedMode == DEGRADED_FROST_PROTECTION {
+		h.Status = climate.HEALTH_FAILED
+		h.Message = "no fresh inside or outside temperature, running frost protection"
+	}
+	return h
+}
+
+func (c *eBusClimate) storeHealth(now time.Time) climate.ComponentHealth {
+	status, ok := c.stateStore.(climate.StoreStatus)
+	if !ok {
+		return component(climate.COMPONENT_STATE_STORE, time.Time{}, now)
+	}
+	lastSave, err := status.LastSave()
+	h := component(climate.COMPONENT_STATE_STORE, lastSave, now)
+	if err != nil {
+		h.Status = climate.HEALTH_DEGRADED
+		h.Message = err.Error()
+	}
+	return h
+}
+
+func (c *eBusClimate) relayHealth(now time.Time) climate.ComponentHealth {
+	h := component(climate.COMPONENT_RELAY, time.Time{}, now)
+	if c.relayErr != nil {
+		h.Status = climate.HEALTH_FAILED
+		h.Message = c.relayErr.Error()
+	} else if c.heatingActive {
+		h.Message = "on"
+	} else {
+		h.Message = "off"
+	}
+	return h
+}
+
+func (c *eBusClimate) cyclerHealth(now time.Time) climate.ComponentHealth {
+	heartbeat := c.cyclerHeartbeat.Load()
+	if heartbeat == 0 {
+		h := component(climate.COMPONENT_CYCLER, time.Time{}, now)
+		h.Status = climate.HEALTH_FAILED
+		h.Message = "cycler not started"
+		return h
+	}
+	h := component(climate.COMPONENT_CYCLER, time.Unix(0, heartbeat), now)
+	if age := now.Sub(time.Unix(0, heartbeat)); age > CYCLER_FAILED {
+		h.Status = climate.HEALTH_FAILED
+		h.Message = fmt.Sprintf("no cycler tick for %s", age.Round(time.Second))
+	}
+	return h
+}
@@ -0,0 +1,36 @@
+package vailant
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/climate"
+)
+
+func TestHealth(t *testing.T) {
+	c := createTestClimate()
+	defer close(c.stopChan)
+
+	health := c.Health()
+	if health.Status() != climate.HEALTH_FAILED {
+		t.Errorf("expected failed before first boiler read and cycler tick, got %s", health.Status())
+	}
+
+	c.boilerUpdated = time.Now()
+	c.cyclerHeartbeat.Store(time.Now().UnixNano())
+	c.SetInsideTemp(climate.SOURCE_HTTP, 20)
+	c.SetOutsideTemp(climate.SOURCE_HTTP, 5)
+	if health := c.Health(); health.Status() != climate.HEALTH_OK {
+		t.Errorf("expected ok, got %+v", health)
+	}
+
+	c.boilerUpdated = time.Now().Add(-BOILER_STALE - time.Minute)
+	if h, _ := c.Health().Component(climate.COMPONENT_EBUSD); h.Status != climate.HEALTH_DEGRADED {
+		t.Errorf("expected stale boiler reads to degrade, got %+v", h)
+	}
+
+	c.cyclerHeartbeat.Store(time.Now().Add(-CYCLER_FAILED - time.Minute).UnixNano())
+	if h, _ := c.Health().Component(climate.COMPONENT_CYCLER); h.Status != climate.HEALTH_FAILED {
+		t.Errorf("expected stuck cycler to fail, got %+v", h)
+	}
+}
@@ -0,0 +1,167 @@
+// Hot water subsystem, drives cylinder temperature through SetModeOverride:
+// eco/comfort schedule, disable window and weekly anti-legionella boost.
+
+package vailant
+
+import (
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/ksimuk/ebus-climate/internal/schedule"
+	"github.com/rs/zerolog/log"
+)
+
+const HW_MODE_ECO = "eco"
+const HW_MODE_COMFORT = "comfort"
+const HW_MODE_OFF = "off"
+const HW_MODE_LEGIONELLA = "legionella"
+
+type hotWater struct {
+	ecoTemp            int
+	comfortTemp        int
+	schedule           *schedule.Schedule
+	modes              []string
+	storageTempMessage string
+
+	legionella        *schedule.Schedule
+	legionellaTemp    float64
+	legionellaHold    time.Duration
+	legionellaTimeout time.Duration
+
+	mode        string    // currently scheduled mode
+	boosting    bool      // anti-legionella boost is running
+	holdStart   time.Time // boost temperature reached at
+	failedAt    string    // boost occurrence which failed, to report once
+	storageTemp float64
+}
+
+func newHotWater(config *config.Config) hotWater {
+	hw := hotWater{
+		ecoTemp:            config.HotWater.EcoTemp,
+		comfortTemp:        config.HotWater.ComfortTemp,
+		storageTempMessage: config.HotWater.StorageTempMessage,
+		legionellaTemp:     float64(config.HotWater.Legionella.Temp),
+		legionellaHold:     time.Duration(config.HotWater.Legionella.Hold * float64(time.Minute)),
+		legionellaTimeout:  time.Duration(config.HotWater.Legionella.Timeout * float64(time.Minute)),
+		storageTemp:        -1,
+	}
+
+	points := []schedule.Point{}
+	for i, p := range config.HotWater.Schedule {
+		if p.Mode != HW_MODE_ECO && p.Mode != HW_MODE_COMFORT && p.Mode != HW_MODE_OFF {
+			log.Error().Msgf("Ignoring hot water schedule point %d with invalid mode %q", i, p.Mode)
+			hw.modes = append(hw.modes, "")
+			continue
+		}
+		point, err := schedule.NewPoint(i, p.Days, p.Time)
+		if err != nil {
+			log.Error().Err(err).Msgf("Ignoring invalid hot water schedule point %d", i)
+			hw.modes = append(hw.modes, "")
+			continue
+		}
+		points = append(points, point)
+		hw.modes = append(hw.modes, p.Mode)
+	}
+	hw.schedule = schedule.New(points)
+
+	if config.HotWater.Legionella.Day != "" {
+		point, err := schedule.NewPoint(0, []string{config.HotWater.Legionella.Day}, config.HotWater.Legionella.Time)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid legionella schedule, boost disabled")
+		} else {
+			hw.legionella = schedule.New([]schedule.Point{point})
+		}
+	}
+	return hw
+}
+
+// readsStorageTemp reports if cylinder temperature has to be read from boiler
+func (hw *hotWater) readsStorageTemp() bool {
+	return !hw.legionella.IsEmpty() && hw.storageTempMessage != ""
+}
+
+// hwTargetTemp returns cylinder temperature sent to boiler
+func (c *eBusClimate) hwTargetTemp() int {
+	if c.hotWater.boosting {
+		return int(c.hotWater.legionellaTemp)
+	}
+	return c.state.HWTargetTemp
+}
+
+// hwLoadDisabled reports if cylinder loading is disabled by schedule
+func (c *eBusClimate) hwLoadDisabled() bool {
+	return !c.hotWater.boosting && c.hotWater.mode == HW_MODE_OFF
+}
+
+func (c *eBusClimate) updateHotWater(now time.Time) {
+	hw := &c.hotWater
+
+	if active, ok := hw.schedule.Active(now); ok {
+		hw.mode = hw.modes[active.Index]
+		applied := active.At.Format(time.RFC3339)
+		if applied != c.state.HWScheduleApplied {
+			c.state.HWScheduleApplied = applied
+			log.Info().Msgf("Hot water schedule switches to %s", hw.mode)
+			switch hw.mode {
+			case HW_MODE_ECO:
+				c.SetHWTargetTemp(hw.ecoTemp)
+			case HW_MODE_COMFORT:
+				c.SetHWTargetTemp(hw.comfortTemp)
+			}
+		}
+	}
+
+	c.updateLegionella(now)
+
+	c.stat.HwMode = hw.mode
+	if hw.boosting {
+		c.stat.HwMode = HW_MODE_LEGIONELLA
+	}
+	c.stat.HwStorageTemp = hw.storageTemp
+	c.stat.LegionellaLast = c.state.LegionellaLast
+}
+
+func (c *eBusClimate) updateLegionella(now time.Time) {
+	hw := &c.hotWater
+	occurrence, ok := hw.legionella.Active(now)
+	if !ok {
+		hw.boosting = false
+		return
+	}
+
+	lastDone, err := time.Parse(time.RFC3339, c.state.LegionellaLast)
+	done := err == nil && !lastDone.Before(occurrence.At)
+	expired := now.Sub(occurrence.At) >= hw.legionellaTimeout
+	boosting := !done && !expired
+
+	if boosting && !hw.boosting {
+		log.Info().Msgf("Starting anti-legionella boost to %.0fC", hw.legionellaTemp)
+		hw.holdStart = time.Time{}
+	}
+	if !done && expired && hw.boosting {
+		at := occurrence.At.Format(time.RFC3339)
+		if hw.failedAt != at {
+			hw.failedAt = at
+			log.Warn().Msgf("Anti-legionella boost not confirmed within %v, cylinder at %.1fC", hw.legionellaTimeout, hw.storageTemp)
+		}
+	}
+	hw.boosting = boosting
+	if !boosting {
+		return
+	}
+
+	// confirm by boiler reading the cylinder was kept at boost temperature
+	if hw.storageTemp < hw.legionellaTemp {
+		hw.holdStart = time.Time{}
+		return
+	}
+	if hw.holdStart.IsZero() {
+		hw.holdStart = now
+	}
+	if now.Sub(hw.holdStart) >= hw.legionellaHold {
+		c.state.LegionellaLast = now.Format(time.RFC3339)
+		hw.boosting = false
+		log.Info().Msgf("Anti-legionella boost confirmed, cylinder at %.1fC", hw.storageTemp)
+		c.stateStore.Save(c.state)
+	}
+}
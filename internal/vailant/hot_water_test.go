@@ -0,0 +1,45 @@
+package vailant
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/config"
+)
+
+func TestLegionellaBoostConfirmedByBoiler(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.HotWater.EcoTemp = 45
+	cfg.HotWater.Schedule = []config.HotWaterSchedulePoint{{Time: "00:00", Mode: HW_MODE_OFF}}
+	cfg.HotWater.StorageTempMessage = "HwcStorageTemp"
+	cfg.HotWater.Legionella.Day = "sun"
+	cfg.HotWater.Legionella.Time = "02:00"
+	cfg.HotWater.Legionella.Temp = 60
+	cfg.HotWater.Legionella.Hold = 10
+	cfg.HotWater.Legionella.Timeout = 120
+
+	c := createTestClimate()
+	c.hotWater = newHotWater(cfg)
+	c.state.HWTargetTemp = 45
+
+	// Sunday 2024-01-07
+	start := time.Date(2024, 1, 7, 2, 0, 0, 0, time.Local)
+	c.updateHotWater(start)
+	if !c.hotWater.boosting || c.hwTargetTemp() != 60 || c.hwLoadDisabled() {
+		t.Fatalf("Expected boost to 60 with loading enabled, got target %d disabled %v", c.hwTargetTemp(), c.hwLoadDisabled())
+	}
+
+	c.onChange(map[string]string{"HwcStorageTemp": "61.5;ok"})
+	c.updateHotWater(start.Add(30 * time.Minute))
+	c.updateHotWater(start.Add(35 * time.Minute))
+	if !c.hotWater.boosting {
+		t.Fatal("Expected boost to hold temperature before confirming")
+	}
+	c.updateHotWater(start.Add(40 * time.Minute))
+	if c.hotWater.boosting || c.state.LegionellaLast == "" {
+		t.Fatal("Expected boost to be confirmed after hold time")
+	}
+	if c.hwTargetTemp() != 45 || !c.hwLoadDisabled() {
+		t.Errorf("Expected scheduled hot water off after boost, got target %d disabled %v", c.hwTargetTemp(), c.hwLoadDisabled())
+	}
+}
@@ -3,11 +3,17 @@ package vailant
 import (
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ksimuk/ebus-climate/internal/climate"
 	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/ksimuk/ebus-climate/internal/cyclelog"
 	"github.com/ksimuk/ebus-climate/internal/ebusd/client"
+	"github.com/ksimuk/ebus-climate/internal/energy"
+	"github.com/ksimuk/ebus-climate/internal/schedule"
+	"github.com/ksimuk/ebus-climate/internal/weather"
 	"github.com/rs/zerolog/log"
 	"periph.io/x/conn/v3/gpio"
 	host "periph.io/x/host/v3"
@@ -41,6 +47,7 @@ type eBusClimate struct {
 	state      *climate.ClimateState
 
 	stopChan chan struct{}
+	workers  sync.WaitGroup // background loops ending on stopChan
 
 	desiredFlowTemp int
 	modulationTemp  int
@@ -54,17 +61,66 @@ type eBusClimate struct {
 	adjustmentRate     float64
 	durationMultiplier float64
 
-	heatingActive bool
+	heatingActive  bool
+	heatingStarted time.Time
+
+	cycleLog   climate.CycleLog
+	cycleMutex sync.Mutex
+	cycle      *climate.Cycle // running cycle
+
+	schedule          *schedule.Schedule
+	scheduleTemps     []float64
+	optimumStart      bool
+	optimumStop       bool
+	defaultWarmupRate float64
+	maxPreheat        float64
+	earlyTarget       float64 // next scheduled target applied ahead of time
+	earlyTargetActive bool
+	warmup            *warmup
+
+	hotWater hotWater
+
+	events      *climate.EventBus
+	boilerState map[string]string // last values read from boiler, for change events
+
+	energy          *energy.Ledger
+	heatingCounter  float64 // raw boiler heating energy counter, negative until read
+	hotWaterCounter float64 // raw boiler hot water energy counter, negative until read
 
 	heatingRelay gpio.PinIO
+	relayErr     error // error of last relay switch
+
+	cyclerHeartbeat atomic.Int64 // unix nanoseconds of last cycler tick
 
 	stat              climate.Stat
 	heatingEndTime    time.Time
 	heatingTimerMutex chan struct{}
 
-	// TODO independant thermometers
-	// external      *bluetooththermostat.BluetoothThermostat
-	// internal      *bluetooththermostat.BluetoothThermostat
+	inside        *climate.Input
+	outside       *climate.Input
+	insideSource  string // source currently used for inside temperature
+	outsideSource string // source currently used for outside temperature
+	windSpeed     *climate.Input
+	irradiance    *climate.Input
+
+	windLossFactor  float64
+	solarGainFactor float64
+
+	degradedMode   string
+	frostDutyCycle float64
+	designTemp     float64
+	boilerUpdated  time.Time // last successful boiler read
+
+	outsideTempMessage string
+	outsideTempCircuit string
+	errorMessage       string
+	boilerError        string // current boiler error code, empty if none
+
+	forecastMutex      sync.Mutex
+	forecast           weather.Forecast
+	forecastLookahead  time.Duration
+	forecastAhead      float64 // average forecast temperature over lookahead
+	forecastAheadValid bool
 }
 
 func New(config *config.Config) *eBusClimate {
@@ -73,12 +129,22 @@ func New(config *config.Config) *eBusClimate {
 		log.Error().Err(err).Msg("Failed to initialize periph.io")
 		return nil
 	}
-	ebusClient := client.New(config, READ_PARAMETERS)
+	hotWater := newHotWater(config)
+	readParameters := READ_PARAMETERS
+	if hotWater.readsStorageTemp() {
+		readParameters = append(append([]string{}, READ_PARAMETERS...), hotWater.storageTempMessage)
+	}
+	ebusClient := client.New(config, readParameters)
 
 	c := eBusClimate{
 		ebusClient:         ebusClient,
 		stopChan:           make(chan struct{}),
 		stateStore:         climate.NewClimateStore(),
+		cycleLog:           cyclelog.New(config.CycleLog),
+		events:             climate.NewEventBus(),
+		energy:             energy.New(config, PER_KWH_ADJUSTMENT),
+		heatingCounter:     -1,
+		hotWaterCounter:    -1,
 		loss3:              config.Climate.Loss3,
 		loss7:              config.Climate.Loss7,
 		power:              config.Climate.Power,
@@ -88,10 +154,26 @@ func New(config *config.Config) *eBusClimate {
 		heatingRelay:       rpi.P1_31,
 		desiredFlowTemp:    DESIRED_FLOW_TEMPERATURE,
 		heatingTimerMutex:  make(chan struct{}, 1),
-		// internal:   addThermometer(config.Climate.InternalSensorMAC),
-		// external:   addThermometer(config.Climate.ExternalSensorMAC),
+		hotWater:           hotWater,
+		inside:             climate.NewInput(sourceSettings(config)),
+		outside:            climate.NewInput(sourceSettings(config)),
+		windSpeed:          climate.NewInput(sourceSettings(config)),
+		irradiance:         climate.NewInput(sourceSettings(config)),
+		windLossFactor:     config.Climate.WindLoss,
+		solarGainFactor:    config.Climate.SolarGain,
+		outsideTempMessage: config.Ebus.OutsideTempMessage,
+		outsideTempCircuit: config.Ebus.OutsideTempCircuit,
+		errorMessage:       config.Ebus.ErrorMessage,
 	}
 	c.heatingTimerMutex <- struct{}{} // initialize mutex
+	c.schedule, c.scheduleTemps = newSchedule(config.Climate.Schedule)
+	c.optimumStart = config.Climate.OptimumStart
+	c.optimumStop = config.Climate.OptimumStop
+	c.defaultWarmupRate = config.Climate.WarmupRate
+	c.maxPreheat = config.Climate.MaxPreheat
+	c.frostDutyCycle = config.Climate.FrostDutyCycle
+	c.designTemp = config.Climate.DesignTemp
+	c.forecastLookahead = time.Duration(config.Forecast.Lookahead * float64(time.Hour))
 
 	c.stat = climate.Stat{
 		UsageHeating:    -1,
@@ -115,10 +197,17 @@ func New(config *config.Config) *eBusClimate {
 	}
 
 	c.StartPolling(POOLING_INTERVAL, c.readBoiler)
+	provider, err := weather.New(config)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create forecast provider")
+	}
+	c.startForecast(provider, time.Duration(config.Forecast.Refresh*float64(time.Minute)))
 	c.startCycler()
 
 	// start timer to save state every minute
+	c.workers.Add(1)
 	go func() {
+		defer c.workers.Done()
 		ticker := time.NewTicker(time.Minute)
 		defer ticker.Stop()
 		for {
@@ -134,14 +223,69 @@ func New(config *config.Config) *eBusClimate {
 	return &c
 }
 
+func (c *eBusClimate) Events() *climate.EventBus {
+	return c.events
+}
+
 func (c *eBusClimate) Info() ([]string, error) {
 	return c.ebusClient.Info()
 }
 
+func (c *eBusClimate) GetBusStats() climate.BusStats {
+	stats := c.ebusClient.Stats()
+	return climate.BusStats{
+		Requests:       stats.Requests,
+		Errors:         stats.Errors,
+		LatencySeconds: stats.Latency.Seconds(),
+	}
+}
+
 func (c *eBusClimate) readBoiler(client *client.Client) {
 	//result :=
 	result := client.ReadAll()
 	c.onChange(result)
+	c.readOutsideTemp(client)
+	c.readBoilerError(client)
+}
+
+// readBoilerError reads current error code of boiler
+func (c *eBusClimate) readBoilerError(client *client.Client) {
+	if c.errorMessage == "" {
+		return
+	}
+	result, err := client.GetFrom("", c.errorMessage)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to read boiler error %s", c.errorMessage)
+		return
+	}
+	boilerError := parseBoilerError(result[0])
+	if boilerError != c.boilerError {
+		if boilerError != "" {
+			log.Warn().Msgf("Boiler reports error %s", boilerError)
+		} else {
+			log.Info().Msgf("Boiler error %s cleared", c.boilerError)
+		}
+		c.events.Publish(climate.EVENT_BOILER, map[string]any{"error": boilerError})
+	}
+	c.boilerError = boilerError
+}
+
+// readOutsideTemp reads outdoor sensor wired to boiler or controller
+func (c *eBusClimate) readOutsideTemp(client *client.Client) {
+	if c.outsideTempMessage == "" {
+		return
+	}
+	result, err := client.GetFrom(c.outsideTempCircuit, c.outsideTempMessage)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to read outside temperature %s", c.outsideTempMessage)
+		return
+	}
+	temp, ok := parseFloat(result[0])
+	if !ok {
+		log.Warn().Msgf("Invalid outside temperature %q from %s", result[0], c.outsideTempMessage)
+		return
+	}
+	c.SetOutsideTemp(climate.SOURCE_EBUS, temp)
 }
 
 // StartPolling starts a timer to read data from ebusClient at the given interval.
@@ -149,7 +293,9 @@ func (c *eBusClimate) StartPolling(interval time.Duration, readFunc func(*client
 	log.Debug().Msg("Start ebus pulling")
 	c.readBoiler(c.ebusClient) // initial read
 
+	c.workers.Add(1)
 	go func() {
+		defer c.workers.Done()
 		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 		for {
@@ -163,20 +309,19 @@ func (c *eBusClimate) StartPolling(interval time.Duration, readFunc func(*client
 	}()
 }
 
-// StopPolling stops the polling timer.
+// StopPolling stops the polling timer and other background loops.
 func (c *eBusClimate) StopPolling() {
 	close(c.stopChan)
 }
 
-// TODO make it temporary override with expiration
-func (c *eBusClimate) SetInsideOverride(temp float64) {
-	c.state.InsideTemp = temp
-	c.stateStore.Save(c.state)
-}
-
-func (c *eBusClimate) SetOutsideOverride(temp float64) {
-	c.state.OutsideTemp = temp
-	c.stateStore.Save(c.state)
+// stopped reports if background loops were stopped by shutdown
+func (c *eBusClimate) stopped() bool {
+	select {
+	case <-c.stopChan:
+		return true
+	default:
+		return false
+	}
 }
 
 func (c *eBusClimate) GetInsideTemp() float64 {
@@ -228,7 +373,7 @@ func (c *eBusClimate) IsConnected() bool {
 }
 
 func (c *eBusClimate) GetError() string {
-	return ""
+	return c.boilerError
 }
 
 func (c *eBusClimate) GetConsumption() float64 {
@@ -245,6 +390,7 @@ func (c *eBusClimate) GetBoilerInfo() climate.BoilerInfo {
 func (c *eBusClimate) SetHWTargetTemp(temp int) error {
 	c.state.HWTargetTemp = temp
 	c.stateStore.Save(c.state)
+	c.events.Publish(climate.EVENT_SETTINGS, map[string]any{"hw_target_temp": temp})
 	return nil
 }
 
@@ -261,38 +407,71 @@ func (c *eBusClimate) SetMode(mode string) error {
 	}
 	c.state.Mode = mode
 	c.stateStore.Save(c.state)
+	c.events.Publish(climate.EVENT_SETTINGS, map[string]any{"mode": mode})
 	return nil
 }
 
 func (c *eBusClimate) SetTargetTemperature(temp float64) error {
 	c.state.TargetTemperature = temp
 	c.stateStore.Save(c.state)
+	c.events.Publish(climate.EVENT_SETTINGS, map[string]any{"target_temperature": temp})
 	return nil
 }
 
 func (c *eBusClimate) OverrideHeating(timeSeconds int) {
-	c.StartHeating()
+	c.startHeating(climate.TRIGGER_OVERRIDE, float64(timeSeconds)/60)
 	time.AfterFunc(time.Duration(timeSeconds)*time.Second, func() {
 		c.StopHeating()
 	})
 }
 
 func (c *eBusClimate) StartHeating() {
+	c.startHeating(climate.TRIGGER_MANUAL, 0)
+}
+
+// startHeating fires boiler, trigger and planned length are recorded in cycle log
+func (c *eBusClimate) startHeating(trigger string, plannedMinutes float64) {
 	if c.state.Mode != MODE_HEATING {
 		// heating is off
 		return
 	}
+	if c.stopped() {
+		log.Warn().Msg("Not starting heating during shutdown")
+		return
+	}
+	if !c.heatingActive {
+		c.stat.Cycles++
+		c.heatingStarted = time.Now()
+		c.beginCycle(trigger, plannedMinutes)
+		c.events.Publish(climate.EVENT_HEATING_STARTED, map[string]any{
+			"gas_active":      true,
+			"trigger":         trigger,
+			"planned_minutes": plannedMinutes,
+			"cycles":          c.stat.Cycles,
+		})
+	}
 	c.heatingActive = true
 	log.Debug().Msg("Starting heating")
-	if err := c.heatingRelay.Out(gpio.High); err != nil {
+	c.relayErr = c.heatingRelay.Out(gpio.High)
+	if err := c.relayErr; err != nil {
 		log.Warn().Err(err).Msg("Failed to set relay pin high")
 	}
 }
 
 func (c *eBusClimate) StopHeating() {
+	if c.heatingActive {
+		burn := time.Since(c.heatingStarted).Seconds()
+		c.stat.BurnSeconds += burn
+		c.endCycle()
+		c.events.Publish(climate.EVENT_HEATING_STOPPED, map[string]any{
+			"gas_active":   false,
+			"burn_seconds": burn,
+		})
+	}
 	c.heatingActive = false
 	log.Debug().Msg("Stopping heating")
-	if err := c.heatingRelay.Out(gpio.Low); err != nil {
+	c.relayErr = c.heatingRelay.Out(gpio.Low)
+	if err := c.relayErr; err != nil {
 		log.Warn().Err(err).Msg("Failed to set relay pin low")
 	}
 }
@@ -312,13 +491,30 @@ func (c *eBusClimate) pingHeating() {
 	// remoteControlHcPump
 	// releaseBackup
 	// releaseCooling
-	command := fmt.Sprintf("0;%d;%d;-;-;0;0;0;-;0;0;0", c.desiredFlowTemp, c.state.HWTargetTemp)
+	disableLoad := 0
+	if c.hwLoadDisabled() {
+		disableLoad = 1
+	}
+	command := fmt.Sprintf("0;%d;%d;-;-;0;0;%d;-;0;0;0", c.desiredFlowTemp, c.hwTargetTemp(), disableLoad)
 	c.ebusClient.Set("SetModeOverride", command)
 }
 
+// Shutdown stops and waits for cycler, forecast and polling loops so nothing fires
+// the boiler again, then ends running cycle and flushes state
 func (c *eBusClimate) Shutdown() {
+	// closed under heating lock so no cycle goroutine starts after wait
+	<-c.heatingTimerMutex
 	c.StopPolling()
+	c.heatingTimerMutex <- struct{}{}
+	c.workers.Wait()
+
+	<-c.heatingTimerMutex
+	c.StopHeating()
+	c.heatingTimerMutex <- struct{}{}
 	c.stateStore.SaveNow(c.state)
+	if c.energy != nil {
+		c.energy.Save()
+	}
 }
 
 func (c *eBusClimate) GetHeatLossBalance() float64 {
@@ -328,10 +524,15 @@ func (c *eBusClimate) GetHeatLossBalance() float64 {
 func (c *eBusClimate) GetStat() climate.Stat {
 	<-c.heatingTimerMutex
 	endTime := c.heatingEndTime
+	active := c.heatingActive
+	started := c.heatingStarted
 	c.heatingTimerMutex <- struct{}{}
 
 	stat := c.stat
-	if c.heatingActive {
+	c.temperatureStat(&stat)
+	stat.BoilerUpdated = c.boilerStat()
+	if active {
+		stat.BurnSeconds += time.Since(started).Seconds() // running burn
 		stat.HeatingEndTime = endTime.Format("2006-01-02T15:04:05Z07:00")
 	} else {
 		stat.HeatingEndTime = ""
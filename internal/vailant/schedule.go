@@ -0,0 +1,178 @@
+// Schedule applies the target temperature program and implements optimum start/stop:
+// heating starts early so the house reaches the next comfort temperature on time,
+// and stops early to coast into setback periods.
+package vailant
+
+import (
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/ksimuk/ebus-climate/internal/schedule"
+	"github.com/rs/zerolog/log"
+)
+
+const WARMUP_LEARNING_RATE = 0.3 // weight of the latest observation in learned warm-up rate
+const MIN_WARMUP_RISE = 0.3      // ignore pre-heat periods with smaller temperature rise
+
+// warmup tracks a pre-heat period to learn how fast the house warms up
+type warmup struct {
+	startTemp float64
+	netHeat   float64 // heat delivered above losses in Wh
+}
+
+func newSchedule(points []config.SchedulePoint) (*schedule.Schedule, []float64) {
+	parsed := []schedule.Point{}
+	temps := []float64{}
+	for i, p := range points {
+		point, err := schedule.NewPoint(i, p.Days, p.Time)
+		if err != nil {
+			log.Error().Err(err).Msgf("Ignoring invalid schedule point %d", i)
+			temps = append(temps, 0)
+			continue
+		}
+		parsed = append(parsed, point)
+		temps = append(temps, p.Temp)
+	}
+	return schedule.New(parsed), temps
+}
+
+// targetTemperature returns target used by heat loss model, including optimum start/stop
+func (c *eBusClimate) targetTemperature() float64 {
+	if c.earlyTargetActive {
+		return c.earlyTarget
+	}
+	return c.state.TargetTemperature
+}
+
+func (c *eBusClimate) warmupRate() float64 {
+	if c.state.WarmupRate > 0 {
+		return c.state.WarmupRate
+	}
+	return c.defaultWarmupRate
+}
+
+// lossAt returns heat loss in W for the house kept at inside temperature
+func (c *eBusClimate) lossAt(inside float64) float64 {
+	weather := c.state.OutsideTemp + BASE_TEMP - inside
+	return float64(c.loss3-c.loss7)/10*(7-weather) + float64(c.loss7)
+}
+
+func (c *eBusClimate) limitPreheat(minutes float64) time.Duration {
+	if minutes > c.maxPreheat {
+		minutes = c.maxPreheat
+	}
+	return time.Duration(minutes * float64(time.Minute))
+}
+
+// preheatDuration estimates time to warm up to target running boiler on full power
+func (c *eBusClimate) preheatDuration(target float64) time.Duration {
+	rise := target - c.state.InsideTemp
+	if rise <= 0 {
+		return 0
+	}
+	net := float64(c.power) - c.lossAt(target)
+	if net <= 0 || c.power <= 0 {
+		return c.limitPreheat(c.maxPreheat)
+	}
+	rate := c.warmupRate() * net / float64(c.power) // C per hour
+	return c.limitPreheat(rise / rate * 60)
+}
+
+// coastDuration estimates time for the house to cool down to target without heating
+func (c *eBusClimate) coastDuration(target float64) time.Duration {
+	drop := c.state.InsideTemp - target
+	if drop <= 0 || c.power <= 0 {
+		return 0
+	}
+	loss := c.lossAt(c.state.InsideTemp)
+	if loss <= 0 {
+		return 0
+	}
+	rate := c.warmupRate() * loss / float64(c.power) // C per hour
+	return c.limitPreheat(drop / rate * 60)
+}
+
+func (c *eBusClimate) updateSchedule(now time.Time) {
+	c.stat.PreheatStart = ""
+	c.stat.SetbackStart = ""
+	c.stat.WarmupRate = c.warmupRate()
+	if c.schedule.IsEmpty() {
+		return
+	}
+
+	if active, ok := c.schedule.Active(now); ok {
+		applied := active.At.Format(time.RFC3339)
+		if applied != c.state.ScheduleApplied {
+			c.finishWarmup()
+			c.state.ScheduleApplied = applied
+			temp := c.scheduleTemps[active.Index]
+			log.Info().Msgf("Schedule sets target temperature to %.1f", temp)
+			c.SetTargetTemperature(temp)
+		}
+	}
+
+	earlyTarget := 0.0
+	early := false
+	// optimum start/stop needs inside temperature
+	if next, ok := c.schedule.Next(now); ok && c.degradedMode == DEGRADED_NONE {
+		nextTemp := c.scheduleTemps[next.Index]
+		current := c.state.TargetTemperature
+		switch {
+		case c.optimumStart && nextTemp > current:
+			start := next.At.Add(-c.preheatDuration(nextTemp))
+			c.stat.PreheatStart = start.Format(time.RFC3339)
+			early = !now.Before(start)
+		case c.optimumStop && nextTemp < current:
+			start := next.At.Add(-c.coastDuration(nextTemp))
+			c.stat.SetbackStart = start.Format(time.RFC3339)
+			early = !now.Before(start)
+		}
+		earlyTarget = nextTemp
+	}
+
+	if early && (!c.earlyTargetActive || c.earlyTarget != earlyTarget) {
+		log.Info().Msgf("Optimum start/stop: switching to target %.1f ahead of schedule", earlyTarget)
+		if earlyTarget > c.state.TargetTemperature {
+			c.warmup = &warmup{startTemp: c.state.InsideTemp}
+		}
+	}
+	if !early && c.earlyTargetActive {
+		// schedule or inputs changed, pre-heat is not valid anymore
+		c.warmup = nil
+	}
+	c.earlyTarget = earlyTarget
+	c.earlyTargetActive = early
+
+	c.trackWarmup()
+}
+
+// trackWarmup accumulates heat delivered during pre-heat
+func (c *eBusClimate) trackWarmup() {
+	if c.warmup == nil {
+		return
+	}
+	delivered := 0.0
+	if c.heatingActive {
+		delivered = float64(c.power)
+	}
+	c.warmup.netHeat += (delivered - c.lossAt(c.state.InsideTemp)) * CYCLE_CHECK_INTERVAL / 60
+}
+
+// finishWarmup updates learned warm-up rate once pre-heat reached the scheduled time
+func (c *eBusClimate) finishWarmup() {
+	w := c.warmup
+	c.warmup = nil
+	if w == nil || c.power <= 0 {
+		return
+	}
+	rise := c.state.InsideTemp - w.startTemp
+	if rise < MIN_WARMUP_RISE || w.netHeat <= 0 {
+		log.Debug().Msgf("Pre-heat finished with rise %f and net heat %f Wh, not learning", rise, w.netHeat)
+		return
+	}
+	capacity := w.netHeat / rise // Wh per C
+	observed := float64(c.power) / capacity
+	rate := c.warmupRate()*(1-WARMUP_LEARNING_RATE) + observed*WARMUP_LEARNING_RATE
+	log.Info().Msgf("Learned warm-up rate %.2f C/h (observed %.2f C/h)", rate, observed)
+	c.state.WarmupRate = rate
+}
@@ -0,0 +1,83 @@
+package vailant
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/config"
+)
+
+func createScheduledClimate(t *testing.T) *eBusClimate {
+	c := createTestClimate()
+	c.power = 7000
+	c.loss3 = 3100
+	c.loss7 = 1300
+	c.defaultWarmupRate = 2.0
+	c.maxPreheat = 180
+	c.optimumStart = true
+	c.optimumStop = true
+	c.schedule, c.scheduleTemps = newSchedule([]config.SchedulePoint{
+		{Time: "06:00", Temp: 21},
+		{Time: "22:00", Temp: 17},
+	})
+	c.state.OutsideTemp = 7
+	return c
+}
+
+func TestPreheatDuration(t *testing.T) {
+	c := createScheduledClimate(t)
+	c.state.InsideTemp = 17
+
+	// net power 7000 - 1300 W (loss at 7C for target 20 +1C) ~ 5.5kW -> rate ~1.57C/h
+	duration := c.preheatDuration(21)
+	if duration < 2*time.Hour || duration > 3*time.Hour {
+		t.Errorf("Expected pre-heat between 2 and 3 hours, got %v", duration)
+	}
+
+	c.state.InsideTemp = 22
+	if d := c.preheatDuration(21); d != 0 {
+		t.Errorf("Expected no pre-heat when already warm, got %v", d)
+	}
+
+	c.state.InsideTemp = 10
+	if d := c.preheatDuration(21); d != 180*time.Minute {
+		t.Errorf("Expected pre-heat limited to max, got %v", d)
+	}
+}
+
+func TestUpdateScheduleStartsEarly(t *testing.T) {
+	c := createScheduledClimate(t)
+	c.state.InsideTemp = 17
+
+	// night setback applied at 22:00
+	c.updateSchedule(time.Date(2024, 1, 1, 1, 0, 0, 0, time.Local))
+	if c.state.TargetTemperature != 17 {
+		t.Fatalf("Expected schedule to set target 17, got %f", c.state.TargetTemperature)
+	}
+	if c.earlyTargetActive {
+		t.Error("Expected no pre-heat five hours ahead")
+	}
+	if c.stat.PreheatStart == "" {
+		t.Error("Expected predicted pre-heat start")
+	}
+
+	c.updateSchedule(time.Date(2024, 1, 1, 5, 0, 0, 0, time.Local))
+	if !c.earlyTargetActive || c.targetTemperature() != 21 {
+		t.Errorf("Expected pre-heat to target 21 one hour ahead, got %f", c.targetTemperature())
+	}
+	if c.warmup == nil {
+		t.Fatal("Expected warm-up tracking during pre-heat")
+	}
+
+	// house warmed by 3C with 7kWh of net heat, capacity 2333 Wh/C -> 3C/h at full power
+	c.warmup.netHeat = 7000
+	c.state.InsideTemp = 20
+	c.updateSchedule(time.Date(2024, 1, 1, 6, 0, 0, 0, time.Local))
+	if c.state.TargetTemperature != 21 {
+		t.Errorf("Expected scheduled target 21, got %f", c.state.TargetTemperature)
+	}
+	expected := 2.0*(1-WARMUP_LEARNING_RATE) + 3.0*WARMUP_LEARNING_RATE
+	if diff := c.state.WarmupRate - expected; diff > 0.01 || diff < -0.01 {
+		t.Errorf("Expected learned warm-up rate %f, got %f", expected, c.state.WarmupRate)
+	}
+}
@@ -0,0 +1,103 @@
+// Inside and outside temperatures are merged from several sources,
+// the highest priority reading which has not expired is used by heat loss model.
+
+package vailant
+
+import (
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/climate"
+	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+func sourceSettings(config *config.Config) map[string]climate.SourceSettings {
+	settings := map[string]climate.SourceSettings{}
+	for source, s := range config.TemperatureSources {
+		settings[source] = climate.SourceSettings{
+			Priority: s.Priority,
+			TTL:      time.Duration(s.TTL * float64(time.Minute)),
+		}
+	}
+	return settings
+}
+
+// SetInsideOverride overrides inside temperature until ttl expires
+func (c *eBusClimate) SetInsideOverride(temp float64, ttl time.Duration) {
+	c.inside.Update(climate.SOURCE_OVERRIDE, temp, ttl, time.Now())
+	c.refreshTemperatures(time.Now())
+}
+
+// SetOutsideOverride overrides outside temperature until ttl expires
+func (c *eBusClimate) SetOutsideOverride(temp float64, ttl time.Duration) {
+	c.outside.Update(climate.SOURCE_OVERRIDE, temp, ttl, time.Now())
+	c.refreshTemperatures(time.Now())
+}
+
+// SetInsideTemp records inside temperature reading from source
+func (c *eBusClimate) SetInsideTemp(source string, temp float64) {
+	c.inside.Update(source, temp, 0, time.Now())
+	c.refreshTemperatures(time.Now())
+}
+
+// SetOutsideTemp records outside temperature reading from source
+func (c *eBusClimate) SetOutsideTemp(source string, temp float64) {
+	c.outside.Update(source, temp, 0, time.Now())
+	c.refreshTemperatures(time.Now())
+}
+
+// SetWindSpeed records wind speed in m/s used for wind compensation
+func (c *eBusClimate) SetWindSpeed(source string, speed float64) {
+	c.windSpeed.Update(source, speed, 0, time.Now())
+}
+
+// SetIrradiance records solar irradiance in W/m2 used for solar gain
+func (c *eBusClimate) SetIrradiance(source string, irradiance float64) {
+	c.irradiance.Update(source, irradiance, 0, time.Now())
+}
+
+// refreshTemperatures selects current inside and outside temperatures, expired sources are demoted
+func (c *eBusClimate) refreshTemperatures(now time.Time) {
+	if value, source, ok := c.selectTemperature("inside", c.inside, c.insideSource, now); ok {
+		c.state.InsideTemp = value
+		c.insideSource = source
+	} else {
+		c.insideSource = ""
+	}
+	if value, source, ok := c.selectTemperature("outside", c.outside, c.outsideSource, now); ok {
+		c.state.OutsideTemp = value
+		c.outsideSource = source
+	} else {
+		c.outsideSource = ""
+	}
+	c.updateDegradedMode()
+	c.stateStore.Save(c.state)
+}
+
+func (c *eBusClimate) selectTemperature(name string, input *climate.Input, previous string, now time.Time) (float64, string, bool) {
+	value, source, _, ok := input.Current(now)
+	if !ok {
+		if previous != "" {
+			log.Warn().Msgf("No fresh %s temperature, %s reading expired", name, previous)
+		}
+		return 0, "", false
+	}
+	if source != previous {
+		log.Info().Msgf("Using %s temperature from %s", name, source)
+	}
+	return value, source, true
+}
+
+func (c *eBusClimate) temperatureStat(stat *climate.Stat) {
+	now := time.Now()
+	if _, _, updated, ok := c.inside.Current(now); ok {
+		stat.InsideTempUpdated = updated.Format(time.RFC3339)
+	}
+	if _, _, updated, ok := c.outside.Current(now); ok {
+		stat.OutsideTempUpdated = updated.Format(time.RFC3339)
+	}
+	stat.InsideTempSource = c.insideSource
+	stat.OutsideTempSource = c.outsideSource
+	stat.InsideSources = c.inside.Status(now)
+	stat.OutsideSources = c.outside.Status(now)
+}
@@ -1,9 +0,0 @@
-package vailant
-
-// func addThermometer(address string) *bluetooththermostat.BluetoothThermostat {
-// 	log.Info().Msgf("Adding thermometer with address: %s", address)
-// 	thermometer := bluetooththermostat.New(address, func(temp, humidity, battery float64) {
-// 		log.Debug().Msgf("Received data from thermometer %s: Temp: %f, Humidity: %f, Battery: %f", address, temp, humidity, battery)
-// 	})
-// 	return thermometer
-// }
@@ -0,0 +1,28 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+)
+
+// File reads forecast from JSON file with list of points, useful for tests and local scripts
+type File struct {
+	path string
+}
+
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+func (f *File) Forecast(ctx context.Context) (Forecast, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	var forecast Forecast
+	if err := json.Unmarshal(data, &forecast); err != nil {
+		return nil, err
+	}
+	return forecast.sorted(), nil
+}
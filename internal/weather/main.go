@@ -0,0 +1,106 @@
+// Package weather provides outside temperature forecast used to anticipate heat loss.
+package weather
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/config"
+)
+
+const PROVIDER_OPEN_METEO = "open-meteo"
+const PROVIDER_MET_NO = "met-no"
+const PROVIDER_FILE = "file"
+
+const REQUEST_TIMEOUT = 30 * time.Second
+
+// Point is forecast for a moment in time
+type Point struct {
+	Time       time.Time `json:"time"`
+	Temp       float64   `json:"temp"`       // outside temperature in C
+	WindSpeed  float64   `json:"wind_speed"` // wind speed in m/s, 0 if unknown
+	Irradiance float64   `json:"irradiance"` // global horizontal irradiance in W/m2, 0 if unknown
+}
+
+// Forecast is ordered by time
+type Forecast []Point
+
+type Provider interface {
+	Forecast(ctx context.Context) (Forecast, error)
+}
+
+// New creates provider from config, nil if forecast is not configured
+func New(config *config.Config) (Provider, error) {
+	cfg := config.Forecast
+	client := &http.Client{Timeout: REQUEST_TIMEOUT}
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case PROVIDER_OPEN_METEO:
+		return &OpenMeteo{latitude: cfg.Latitude, longitude: cfg.Longitude, url: cfg.URL, client: client}, nil
+	case PROVIDER_MET_NO:
+		return &MetNo{latitude: cfg.Latitude, longitude: cfg.Longitude, url: cfg.URL, client: client}, nil
+	case PROVIDER_FILE:
+		return &File{path: cfg.File}, nil
+	}
+	return nil, fmt.Errorf("unknown forecast provider %q", cfg.Provider)
+}
+
+func (f Forecast) sorted() Forecast {
+	sorted := append(Forecast{}, f...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+	return sorted
+}
+
+// At interpolates forecast at time t, false if t is outside of forecast range
+func (f Forecast) At(t time.Time) (Point, bool) {
+	if len(f) == 0 || t.Before(f[0].Time) || t.After(f[len(f)-1].Time) {
+		return Point{}, false
+	}
+	for i := 1; i < len(f); i++ {
+		if t.After(f[i].Time) {
+			continue
+		}
+		prev, next := f[i-1], f[i]
+		span := next.Time.Sub(prev.Time).Seconds()
+		if span <= 0 {
+			return next, true
+		}
+		ratio := t.Sub(prev.Time).Seconds() / span
+		return Point{
+			Time:       t,
+			Temp:       prev.Temp + (next.Temp-prev.Temp)*ratio,
+			WindSpeed:  prev.WindSpeed + (next.WindSpeed-prev.WindSpeed)*ratio,
+			Irradiance: prev.Irradiance + (next.Irradiance-prev.Irradiance)*ratio,
+		}, true
+	}
+	return f[0], true
+}
+
+// Average returns average temperature between from and to sampled every 10 minutes
+func (f Forecast) Average(from, to time.Time) (Point, bool) {
+	sum := Point{}
+	count := 0
+	for t := from; !t.After(to); t = t.Add(10 * time.Minute) {
+		p, ok := f.At(t)
+		if !ok {
+			continue
+		}
+		sum.Temp += p.Temp
+		sum.WindSpeed += p.WindSpeed
+		sum.Irradiance += p.Irradiance
+		count++
+	}
+	if count == 0 {
+		return Point{}, false
+	}
+	return Point{
+		Time:       from,
+		Temp:       sum.Temp / float64(count),
+		WindSpeed:  sum.WindSpeed / float64(count),
+		Irradiance: sum.Irradiance / float64(count),
+	}, true
+}
@@ -0,0 +1,58 @@
+package weather
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileForecastInterpolation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "forecast.json")
+	data := `[
+		{"time": "2024-01-01T13:00:00Z", "temp": -2},
+		{"time": "2024-01-01T12:00:00Z", "temp": 4}
+	]`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	forecast, err := NewFile(path).Forecast(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, ok := forecast.At(time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC))
+	if !ok || p.Temp != 1 {
+		t.Errorf("Expected interpolated 1C, got %v %v", p.Temp, ok)
+	}
+	if _, ok := forecast.At(time.Date(2024, 1, 1, 14, 0, 0, 0, time.UTC)); ok {
+		t.Error("Expected no value past the end of forecast")
+	}
+
+	avg, ok := forecast.Average(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC))
+	if !ok || avg.Temp < 0.99 || avg.Temp > 1.01 {
+		t.Errorf("Expected average 1C, got %v", avg.Temp)
+	}
+}
+
+func TestOpenMeteoForecast(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("latitude") == "" || r.URL.Query().Get("hourly") == "" {
+			t.Errorf("Unexpected query %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"hourly": {"time": [1704110400, 1704114000], "temperature_2m": [4.5, 3.5]}}`))
+	}))
+	defer server.Close()
+
+	provider := &OpenMeteo{latitude: 51.5, longitude: -0.1, url: server.URL, client: server.Client()}
+	forecast, err := provider.Forecast(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forecast) != 2 || forecast[0].Temp != 4.5 || !forecast[1].Time.Equal(time.Unix(1704114000, 0)) {
+		t.Errorf("Unexpected forecast %+v", forecast)
+	}
+}
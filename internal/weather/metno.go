@@ -0,0 +1,55 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const MET_NO_URL = "https://api.met.no/weatherapi/locationforecast/2.0/compact"
+
+// MetNo fetches forecast from Norwegian Meteorological Institute
+type MetNo struct {
+	latitude  float64
+	longitude float64
+	url       string
+	client    *http.Client
+}
+
+type metNoResponse struct {
+	Properties struct {
+		Timeseries []struct {
+			Time time.Time `json:"time"`
+			Data struct {
+				Instant struct {
+					Details struct {
+						AirTemperature float64 `json:"air_temperature"`
+						WindSpeed      float64 `json:"wind_speed"`
+					} `json:"details"`
+				} `json:"instant"`
+			} `json:"data"`
+		} `json:"timeseries"`
+	} `json:"properties"`
+}
+
+func (m *MetNo) Forecast(ctx context.Context) (Forecast, error) {
+	base := m.url
+	if base == "" {
+		base = MET_NO_URL
+	}
+	// met.no asks to limit coordinates to 4 decimals
+	url := fmt.Sprintf("%s?lat=%.4f&lon=%.4f", base, m.latitude, m.longitude)
+
+	var response metNoResponse
+	if err := getJSON(ctx, m.client, url, &response); err != nil {
+		return nil, err
+	}
+	forecast := Forecast{}
+	for _, entry := range response.Properties.Timeseries {
+		details := entry.Data.Instant.Details
+		// compact forecast has no irradiance
+		forecast = append(forecast, Point{Time: entry.Time, Temp: details.AirTemperature, WindSpeed: details.WindSpeed})
+	}
+	return forecast.sorted(), nil
+}
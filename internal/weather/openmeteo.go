@@ -0,0 +1,82 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const OPEN_METEO_URL = "https://api.open-meteo.com/v1/forecast"
+
+// OpenMeteo fetches hourly forecast from open-meteo.com
+type OpenMeteo struct {
+	latitude  float64
+	longitude float64
+	url       string
+	client    *http.Client
+}
+
+type openMeteoResponse struct {
+	Hourly struct {
+		Time        []int64   `json:"time"`
+		Temperature []float64 `json:"temperature_2m"`
+		WindSpeed   []float64 `json:"wind_speed_10m"`
+		Radiation   []float64 `json:"shortwave_radiation"`
+	} `json:"hourly"`
+}
+
+func (o *OpenMeteo) Forecast(ctx context.Context) (Forecast, error) {
+	base := o.url
+	if base == "" {
+		base = OPEN_METEO_URL
+	}
+	query := url.Values{}
+	query.Set("latitude", fmt.Sprintf("%f", o.latitude))
+	query.Set("longitude", fmt.Sprintf("%f", o.longitude))
+	query.Set("hourly", "temperature_2m,wind_speed_10m,shortwave_radiation")
+	query.Set("wind_speed_unit", "ms")
+	query.Set("timeformat", "unixtime")
+	query.Set("forecast_days", "2")
+
+	var response openMeteoResponse
+	if err := getJSON(ctx, o.client, base+"?"+query.Encode(), &response); err != nil {
+		return nil, err
+	}
+	hourly := response.Hourly
+	if len(hourly.Time) != len(hourly.Temperature) {
+		return nil, fmt.Errorf("open-meteo returned %d times and %d temperatures", len(hourly.Time), len(hourly.Temperature))
+	}
+	forecast := Forecast{}
+	for i, t := range hourly.Time {
+		point := Point{Time: time.Unix(t, 0), Temp: hourly.Temperature[i]}
+		if i < len(hourly.WindSpeed) {
+			point.WindSpeed = hourly.WindSpeed[i]
+		}
+		if i < len(hourly.Radiation) {
+			point.Irradiance = hourly.Radiation[i]
+		}
+		forecast = append(forecast, point)
+	}
+	return forecast.sorted(), nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, result any) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	// met.no requires identification
+	request.Header.Set("User-Agent", "ebus-climate github.com/ksimuk/ebus-climate")
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("forecast request failed with status %s", response.Status)
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
@@ -0,0 +1,404 @@
+package web
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/climate"
+	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/ksimuk/ebus-climate/internal/vailant"
+	"github.com/rs/zerolog/log"
+)
+
+const API_PREFIX = "/api/v1"
+
+const ERROR_INVALID_ARGUMENT = "invalid_argument"
+const ERROR_NOT_FOUND = "not_found"
+const ERROR_METHOD_NOT_ALLOWED = "method_not_allowed"
+const ERROR_UNAUTHORIZED = "unauthorized"
+const ERROR_FORBIDDEN = "forbidden"
+const ERROR_INTERNAL = "internal"
+const ERROR_UNAVAILABLE = "unavailable"
+
+const MAX_FORCE_MINUTES = 120
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+// APIError is error body of /api/v1
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+type apiErrorBody struct {
+	Error APIError `json:"error"`
+}
+
+// Settings is partial settings update, nil fields are left unchanged
+type Settings struct {
+	Mode              *string  `json:"mode,omitempty"` // off, heating
+	TargetTemperature *float64 `json:"target_temperature,omitempty"`
+	HWTargetTemp      *int     `json:"hw_target_temp,omitempty"` // 0 disables hot water target
+}
+
+// Override sets temperatures and weather inputs, nil fields are left unchanged
+type Override struct {
+	Source      string   `json:"source,omitempty"` // override (default) or http
+	TTLMinutes  float64  `json:"ttl_minutes,omitempty"`
+	InsideTemp  *float64 `json:"inside_temp,omitempty"`
+	OutsideTemp *float64 `json:"outside_temp,omitempty"`
+	WindSpeed   *float64 `json:"wind_speed,omitempty"`
+	Irradiance  *float64 `json:"irradiance,omitempty"`
+}
+
+type Heating struct {
+	Minutes int `json:"minutes"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err APIError) {
+	writeJSON(w, status, apiErrorBody{Error: err})
+}
+
+// decode reads strict JSON body, unknown fields are rejected
+func decode(r *http.Request, value any) *APIError {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		return &APIError{Code: ERROR_INVALID_ARGUMENT, Message: fmt.Sprintf("invalid request body: %v", err)}
+	}
+	return nil
+}
+
+// apiRoute is handler of a method on a path
+type apiRoute struct {
+	scope   string
+	handler http.HandlerFunc
+}
+
+// apiHandler routes /api/v1 with method checks, auth scope by route and JSON errors
+func (s *Server) apiHandler(auth *Authenticator) http.Handler {
+	legacy := func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) { jsonErrors(w, r, handler) }
+	}
+	routes := map[string]map[string]apiRoute{
+		"/state": {
+			http.MethodGet: {config.SCOPE_READ, s.apiGetState},
+		},
+		"/settings": {
+			http.MethodGet:   {config.SCOPE_READ, s.apiGetSettings},
+			http.MethodPatch: {config.SCOPE_CONTROL, s.apiPatchSettings},
+		},
+		"/override": {
+			http.MethodPost: {config.SCOPE_CONTROL, s.apiOverride},
+		},
+		"/heating": {
+			http.MethodPost: {config.SCOPE_CONTROL, s.apiHeating},
+		},
+		"/history":     {http.MethodGet: {config.SCOPE_READ, legacy(s.handleHistory)}},
+		"/cycles":      {http.MethodGet: {config.SCOPE_READ, legacy(s.handleCycles)}},
+		"/energy":      {http.MethodGet: {config.SCOPE_READ, legacy(s.handleEnergy)}},
+		"/degree_days": {http.MethodGet: {config.SCOPE_READ, legacy(s.handleDegreeDays)}},
+		"/events":      {http.MethodGet: {config.SCOPE_READ, s.handleEvents}},
+		"/audit":       {http.MethodGet: {config.SCOPE_READ, legacy(s.handleAudit)}},
+		"/openapi.json": {http.MethodGet: {"", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(openAPISpec)
+		}}},
+	}
+	for _, methods := range routes {
+		for method, route := range methods {
+			if route.scope == config.SCOPE_CONTROL {
+				route.handler = s.apiCommands(route.handler)
+			}
+			if route.scope != "" {
+				route.handler = auth.Require(route.scope, apiAuthError, route.handler).ServeHTTP
+			}
+			methods[method] = route
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, API_PREFIX), "/")
+		methods, ok := routes[path]
+		if !ok {
+			writeError(w, http.StatusNotFound, APIError{Code: ERROR_NOT_FOUND, Message: "unknown endpoint " + r.URL.Path})
+			return
+		}
+		route, ok := methods[r.Method]
+		if !ok {
+			allowed := []string{}
+			for method := range methods {
+				allowed = append(allowed, method)
+			}
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeError(w, http.StatusMethodNotAllowed, APIError{Code: ERROR_METHOD_NOT_ALLOWED, Message: r.Method + " is not allowed on " + r.URL.Path})
+			return
+		}
+		route.handler(w, r)
+	})
+}
+
+// apiAuthError writes authentication failure as JSON error
+func apiAuthError(w http.ResponseWriter, status int, message string) {
+	code := ERROR_FORBIDDEN
+	if status == http.StatusUnauthorized {
+		code = ERROR_UNAUTHORIZED
+	}
+	writeError(w, status, APIError{Code: code, Message: message})
+}
+
+// apiCommands rejects cross-site control requests and those sent once shutdown started
+func (s *Server) apiCommands(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if crossSite(r) {
+			writeError(w, http.StatusForbidden, APIError{Code: ERROR_FORBIDDEN, Message: "cross-site request rejected"})
+			return
+		}
+		if s.draining.Load() {
+			writeError(w, http.StatusServiceUnavailable, APIError{Code: ERROR_UNAVAILABLE, Message: "shutting down"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) apiGetState(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.getState())
+}
+
+func (s *Server) currentSettings() Settings {
+	mode := s.climate.GetMode()
+	target := s.climate.GetTargetTemperature()
+	hw := s.climate.GetHWTargetTemp()
+	return Settings{Mode: &mode, TargetTemperature: &target, HWTargetTemp: &hw}
+}
+
+func (s *Server) apiGetSettings(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.currentSettings())
+}
+
+// validate checks all fields before any is applied
+func (s *Server) validateSettings(settings Settings) *APIError {
+	limits := s.config.Limits
+	if settings.Mode != nil && *settings.Mode != vailant.MODE_HEATING && *settings.Mode != vailant.MODE_OFF {
+		return &APIError{Code: ERROR_INVALID_ARGUMENT, Field: "mode", Message: fmt.Sprintf("mode must be %s or %s", vailant.MODE_HEATING, vailant.MODE_OFF)}
+	}
+	if t := settings.TargetTemperature; t != nil && (*t < limits.MinTargetTemp || *t > limits.MaxTargetTemp) {
+		return &APIError{Code: ERROR_INVALID_ARGUMENT, Field: "target_temperature",
+			Message: fmt.Sprintf("target_temperature must be between %g and %g", limits.MinTargetTemp, limits.MaxTargetTemp)}
+	}
+	if t := settings.HWTargetTemp; t != nil && *t != 0 && (*t < limits.MinHWTargetTemp || *t > limits.MaxHWTargetTemp) {
+		return &APIError{Code: ERROR_INVALID_ARGUMENT, Field: "hw_target_temp",
+			Message: fmt.Sprintf("hw_target_temp must be 0 or between %d and %d", limits.MinHWTargetTemp, limits.MaxHWTargetTemp)}
+	}
+	return nil
+}
+
+// applySettings applies all fields or none, applied fields are restored if a later one fails
+func (s *Server) applySettings(settings Settings) error {
+	s.settingsMutex.Lock()
+	defer s.settingsMutex.Unlock()
+
+	previous := s.currentSettings()
+	rollback := []func(){}
+	fail := func(err error) error {
+		for i := len(rollback) - 1; i >= 0; i-- {
+			rollback[i]()
+		}
+		return err
+	}
+	if settings.Mode != nil && *settings.Mode != *previous.Mode {
+		if err := s.climate.SetMode(*settings.Mode); err != nil {
+			return fail(fmt.Errorf("failed to set mode: %w", err))
+		}
+		rollback = append(rollback, func() { s.climate.SetMode(*previous.Mode) })
+	}
+	if settings.TargetTemperature != nil && *settings.TargetTemperature != *previous.TargetTemperature {
+		if err := s.climate.SetTargetTemperature(*settings.TargetTemperature); err != nil {
+			return fail(fmt.Errorf("failed to set target temperature: %w", err))
+		}
+		rollback = append(rollback, func() { s.climate.SetTargetTemperature(*previous.TargetTemperature) })
+	}
+	if settings.HWTargetTemp != nil && *settings.HWTargetTemp != *previous.HWTargetTemp {
+		if err := s.climate.SetHWTargetTemp(*settings.HWTargetTemp); err != nil {
+			return fail(fmt.Errorf("failed to set hot water target temperature: %w", err))
+		}
+	}
+	return nil
+}
+
+func (s *Server) apiPatchSettings(w http.ResponseWriter, r *http.Request) {
+	var settings Settings
+	if err := decode(r, &settings); err != nil {
+		writeError(w, http.StatusBadRequest, *err)
+		return
+	}
+	if err := s.validateSettings(settings); err != nil {
+		writeError(w, http.StatusBadRequest, *err)
+		return
+	}
+	log.Info().Msgf("API settings update: %s", settingsString(settings))
+	after := settingsValues(settings)
+	before := s.previousValues(after)
+	if err := s.applySettings(settings); err != nil {
+		log.Error().Err(err).Msg("Failed to apply settings")
+		s.recordAudit(r, before, after, err)
+		writeError(w, http.StatusInternalServerError, APIError{Code: ERROR_INTERNAL, Message: err.Error()})
+		return
+	}
+	s.recordAudit(r, before, after, nil)
+	writeJSON(w, http.StatusOK, s.currentSettings())
+}
+
+func settingsString(settings Settings) string {
+	parts := []string{}
+	if settings.Mode != nil {
+		parts = append(parts, "mode="+*settings.Mode)
+	}
+	if settings.TargetTemperature != nil {
+		parts = append(parts, fmt.Sprintf("target_temperature=%g", *settings.TargetTemperature))
+	}
+	if settings.HWTargetTemp != nil {
+		parts = append(parts, fmt.Sprintf("hw_target_temp=%d", *settings.HWTargetTemp))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (s *Server) apiOverride(w http.ResponseWriter, r *http.Request) {
+	var override Override
+	if err := decode(r, &override); err != nil {
+		writeError(w, http.StatusBadRequest, *err)
+		return
+	}
+	if override.Source == "" {
+		override.Source = climate.SOURCE_OVERRIDE
+	}
+	if override.Source != climate.SOURCE_OVERRIDE && override.Source != climate.SOURCE_HTTP {
+		writeError(w, http.StatusBadRequest, APIError{Code: ERROR_INVALID_ARGUMENT, Field: "source", Message: "source must be override or http"})
+		return
+	}
+	if override.TTLMinutes < 0 {
+		writeError(w, http.StatusBadRequest, APIError{Code: ERROR_INVALID_ARGUMENT, Field: "ttl_minutes", Message: "ttl_minutes must not be negative"})
+		return
+	}
+	if override.WindSpeed != nil && *override.WindSpeed < 0 {
+		writeError(w, http.StatusBadRequest, APIError{Code: ERROR_INVALID_ARGUMENT, Field: "wind_speed", Message: "wind_speed must not be negative"})
+		return
+	}
+	if override.Irradiance != nil && *override.Irradiance < 0 {
+		writeError(w, http.StatusBadRequest, APIError{Code: ERROR_INVALID_ARGUMENT, Field: "irradiance", Message: "irradiance must not be negative"})
+		return
+	}
+
+	after := overrideValues(override)
+	before := s.previousValues(after)
+	s.applyOverride(override)
+	s.auditOverride(r, before, after, override)
+	writeJSON(w, http.StatusOK, s.getState())
+}
+
+// applyOverride sets validated override values
+func (s *Server) applyOverride(override Override) {
+	ttl := time.Duration(override.TTLMinutes * float64(time.Minute))
+	if override.InsideTemp != nil {
+		log.Info().Msgf("Setting inside temperature to %g from %s", *override.InsideTemp, override.Source)
+		if override.Source == climate.SOURCE_OVERRIDE {
+			s.climate.SetInsideOverride(*override.InsideTemp, ttl)
+		} else {
+			s.climate.SetInsideTemp(override.Source, *override.InsideTemp)
+		}
+	}
+	if override.OutsideTemp != nil {
+		log.Info().Msgf("Setting outside temperature to %g from %s", *override.OutsideTemp, override.Source)
+		if override.Source == climate.SOURCE_OVERRIDE {
+			s.climate.SetOutsideOverride(*override.OutsideTemp, ttl)
+		} else {
+			s.climate.SetOutsideTemp(override.Source, *override.OutsideTemp)
+		}
+	}
+	if override.WindSpeed != nil {
+		s.climate.SetWindSpeed(override.Source, *override.WindSpeed)
+	}
+	if override.Irradiance != nil {
+		s.climate.SetIrradiance(override.Source, *override.Irradiance)
+	}
+}
+
+func (s *Server) apiHeating(w http.ResponseWriter, r *http.Request) {
+	var heating Heating
+	if err := decode(r, &heating); err != nil {
+		writeError(w, http.StatusBadRequest, *err)
+		return
+	}
+	if heating.Minutes < 1 || heating.Minutes > MAX_FORCE_MINUTES {
+		writeError(w, http.StatusBadRequest, APIError{Code: ERROR_INVALID_ARGUMENT, Field: "minutes",
+			Message: fmt.Sprintf("minutes must be between 1 and %d", MAX_FORCE_MINUTES)})
+		return
+	}
+	log.Info().Msgf("API forcing heating for %d minutes", heating.Minutes)
+	before := s.previousValues(map[string]any{"heating_end_time": nil})
+	s.climate.RunFor(heating.Minutes)
+	s.recordAudit(r, before, map[string]any{"minutes": heating.Minutes}, nil)
+	writeJSON(w, http.StatusAccepted, heating)
+}
+
+// errorCapture turns plain text errors of shared handlers into JSON errors
+type errorCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *errorCapture) WriteHeader(status int) {
+	if status >= http.StatusBadRequest {
+		c.status = status
+		return
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *errorCapture) Write(data []byte) (int, error) {
+	if c.status != 0 {
+		return c.body.Write(data)
+	}
+	return c.ResponseWriter.Write(data)
+}
+
+func (c *errorCapture) Flush() {
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok && c.status == 0 {
+		flusher.Flush()
+	}
+}
+
+func jsonErrors(w http.ResponseWriter, r *http.Request, handler http.HandlerFunc) {
+	capture := &errorCapture{ResponseWriter: w}
+	handler(capture, r)
+	if capture.status == 0 {
+		return
+	}
+	code := ERROR_INTERNAL
+	switch {
+	case capture.status == http.StatusNotFound:
+		code = ERROR_NOT_FOUND
+	case capture.status == http.StatusServiceUnavailable:
+		code = ERROR_UNAVAILABLE
+	case capture.status < http.StatusInternalServerError:
+		code = ERROR_INVALID_ARGUMENT
+	}
+	writeError(w, capture.status, APIError{Code: code, Message: strings.TrimSpace(capture.body.String())})
+}
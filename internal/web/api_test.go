@@ -0,0 +1,162 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/audit"
+	"github.com/ksimuk/ebus-climate/internal/climate"
+	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/ksimuk/ebus-climate/internal/vailant"
+)
+
+// stubClimate keeps settings in memory, other methods are not used
+type stubClimate struct {
+	climate.Climate
+	mode     string
+	target   float64
+	hw       int
+	failHW   bool
+	runFor   int
+	shutdown bool
+	ebusd    string
+	inside   float64
+}
+
+func (s *stubClimate) GetMode() string                      { return s.mode }
+func (s *stubClimate) GetTargetTemperature() float64        { return s.target }
+func (s *stubClimate) GetHWTargetTemp() int                 { return s.hw }
+func (s *stubClimate) SetMode(mode string) error            { s.mode = mode; return nil }
+func (s *stubClimate) SetTargetTemperature(t float64) error { s.target = t; return nil }
+func (s *stubClimate) Health() climate.Health {
+	return climate.Health{Components: []climate.ComponentHealth{
+		{Name: climate.COMPONENT_CYCLER, Status: climate.HEALTH_OK},
+		{Name: climate.COMPONENT_EBUSD, Status: s.ebusd},
+	}}
+}
+func (s *stubClimate) Shutdown()          { s.shutdown = true }
+func (s *stubClimate) RunFor(minutes int) { s.runFor = minutes }
+func (s *stubClimate) SetHWTargetTemp(t int) error {
+	if s.failHW {
+		return errors.New("bus error")
+	}
+	s.hw = t
+	return nil
+}
+
+func (s *stubClimate) SetInsideOverride(t float64, ttl time.Duration) { s.inside = t }
+
+func testAPI(c *stubClimate) http.Handler {
+	cfg := config.Config{}
+	cfg.Limits.MinTargetTemp = 5
+	cfg.Limits.MaxTargetTemp = 30
+	cfg.Limits.MinHWTargetTemp = 35
+	cfg.Limits.MaxHWTargetTemp = 65
+	s := &Server{climate: c, config: cfg, settingsMutex: &sync.Mutex{}}
+	return s.apiHandler(NewAuthenticator(cfg.Auth))
+}
+
+func request(h http.Handler, method, path, body string) (*httptest.ResponseRecorder, apiErrorBody) {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	var e apiErrorBody
+	if w.Code >= 400 {
+		json.Unmarshal(w.Body.Bytes(), &e)
+	}
+	return w, e
+}
+
+func TestAPISettings(t *testing.T) {
+	c := &stubClimate{mode: vailant.MODE_OFF, target: 20, hw: 50}
+	h := testAPI(c)
+
+	// zero is a valid value, omitted fields are unchanged
+	w, _ := request(h, http.MethodPatch, "/api/v1/settings", `{"hw_target_temp": 0}`)
+	if w.Code != http.StatusOK || c.hw != 0 || c.target != 20 || c.mode != vailant.MODE_OFF {
+		t.Errorf("unexpected result %d, climate %+v", w.Code, c)
+	}
+
+	// validation fails before anything is applied
+	w, e := request(h, http.MethodPatch, "/api/v1/settings", `{"mode": "heating", "target_temperature": 45}`)
+	if w.Code != http.StatusBadRequest || e.Error.Field != "target_temperature" || c.mode != vailant.MODE_OFF {
+		t.Errorf("expected validation error, got %d %+v, climate %+v", w.Code, e, c)
+	}
+
+	// failure applying a later field restores earlier ones
+	c.failHW = true
+	w, e = request(h, http.MethodPatch, "/api/v1/settings", `{"mode": "heating", "target_temperature": 21, "hw_target_temp": 55}`)
+	if w.Code != http.StatusInternalServerError || e.Error.Code != ERROR_INTERNAL {
+		t.Errorf("expected internal error, got %d %+v", w.Code, e)
+	}
+	if c.mode != vailant.MODE_OFF || c.target != 20 {
+		t.Errorf("expected rollback, climate %+v", c)
+	}
+
+	w, e = request(h, http.MethodPatch, "/api/v1/settings", `{"target": 21}`)
+	if w.Code != http.StatusBadRequest || e.Error.Code != ERROR_INVALID_ARGUMENT {
+		t.Errorf("expected unknown field to be rejected, got %d %+v", w.Code, e)
+	}
+}
+
+func TestAPIRouting(t *testing.T) {
+	c := &stubClimate{mode: vailant.MODE_HEATING, target: 20}
+	h := testAPI(c)
+
+	w, e := request(h, http.MethodPost, "/api/v1/settings", `{}`)
+	if w.Code != http.StatusMethodNotAllowed || e.Error.Code != ERROR_METHOD_NOT_ALLOWED || w.Header().Get("Allow") == "" {
+		t.Errorf("expected method not allowed, got %d %+v", w.Code, e)
+	}
+	w, e = request(h, http.MethodGet, "/api/v1/unknown", "")
+	if w.Code != http.StatusNotFound || e.Error.Code != ERROR_NOT_FOUND {
+		t.Errorf("expected not found, got %d %+v", w.Code, e)
+	}
+	w, _ = request(h, http.MethodPost, "/api/v1/heating", `{"minutes": 15}`)
+	if w.Code != http.StatusAccepted || c.runFor != 15 {
+		t.Errorf("expected heating for 15 minutes, got %d %d", w.Code, c.runFor)
+	}
+	w, e = request(h, http.MethodGet, "/api/v1/cycles?from=yesterday", "")
+	if w.Code != http.StatusBadRequest || e.Error.Message == "" || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		t.Errorf("expected JSON error from shared handler, got %d %q", w.Code, w.Body.String())
+	}
+	w, _ = request(h, http.MethodGet, "/api/v1/openapi.json", "")
+	if w.Code != http.StatusOK || !json.Valid(w.Body.Bytes()) {
+		t.Errorf("expected OpenAPI document, got %d", w.Code)
+	}
+}
+
+func TestAPIAudit(t *testing.T) {
+	c := &stubClimate{mode: vailant.MODE_OFF, target: 20, hw: 50}
+	cfg := config.Config{}
+	cfg.Limits.MaxTargetTemp = 30
+	cfg.Auth.Tokens = []config.AuthToken{{Name: "kitchen", Token: "secret", Scope: config.SCOPE_CONTROL}}
+	s := &Server{climate: c, config: cfg, settingsMutex: &sync.Mutex{}, audit: audit.New(filepath.Join(t.TempDir(), "audit.jsonl"))}
+	h := s.apiHandler(NewAuthenticator(cfg.Auth))
+
+	r := httptest.NewRequest(http.MethodPatch, "/api/v1/settings", strings.NewReader(`{"target_temperature": 22}`))
+	r.Header.Set("Authorization", "Bearer secret")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/v1/audit", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	var entries []audit.Entry
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil || len(entries) != 1 {
+		t.Fatalf("expected one audit entry, got %d %q", w.Code, w.Body.String())
+	}
+	entry := entries[0]
+	if entry.Principal != "kitchen" || entry.Auth != AUTH_TOKEN || entry.Endpoint != "PATCH /api/v1/settings" || entry.Source == "" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.Old["target_temperature"] != 20.0 || entry.New["target_temperature"] != 22.0 {
+		t.Errorf("unexpected values %+v %+v", entry.Old, entry.New)
+	}
+}
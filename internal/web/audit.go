@@ -0,0 +1,135 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/audit"
+	"github.com/ksimuk/ebus-climate/internal/climate"
+	"github.com/rs/zerolog/log"
+)
+
+// recordAudit logs action of request with values before and after, err marks failed action
+func (s *Server) recordAudit(r *http.Request, before, after map[string]any, err error) {
+	if s.audit == nil {
+		return
+	}
+	entry := audit.Entry{
+		Time:     time.Now(),
+		Source:   r.RemoteAddr,
+		Endpoint: r.Method + " " + r.URL.Path,
+		Old:      before,
+		New:      after,
+	}
+	if principal, ok := PrincipalFrom(r); ok {
+		entry.Principal = principal.Name
+		entry.Auth = principal.Method
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if err := s.audit.Append(entry); err != nil {
+		log.Error().Err(err).Msgf("Failed to write audit entry for %s", entry.Endpoint)
+	}
+}
+
+// settingsValues returns set fields of settings
+func settingsValues(settings Settings) map[string]any {
+	values := map[string]any{}
+	if settings.Mode != nil {
+		values["mode"] = *settings.Mode
+	}
+	if settings.TargetTemperature != nil {
+		values["target_temperature"] = *settings.TargetTemperature
+	}
+	if settings.HWTargetTemp != nil {
+		values["hw_target_temp"] = *settings.HWTargetTemp
+	}
+	return values
+}
+
+// overrideValues returns set values of override
+func overrideValues(override Override) map[string]any {
+	values := map[string]any{}
+	for key, value := range map[string]*float64{
+		"inside_temp":  override.InsideTemp,
+		"outside_temp": override.OutsideTemp,
+		"wind_speed":   override.WindSpeed,
+		"irradiance":   override.Irradiance,
+	} {
+		if value != nil {
+			values[key] = *value
+		}
+	}
+	return values
+}
+
+// auditOverride records manual override or forced heating,
+// pushed sensor readings are kept in history instead
+func (s *Server) auditOverride(r *http.Request, before, after map[string]any, override Override) {
+	_, forced := after["force_heating"]
+	if !forced && (override.Source != climate.SOURCE_OVERRIDE || len(after) == 0) {
+		return
+	}
+	after["source"] = override.Source
+	if override.TTLMinutes > 0 {
+		after["ttl_minutes"] = override.TTLMinutes
+	}
+	s.recordAudit(r, before, after, nil)
+}
+
+// previousValues returns current values of keys about to change, nil if audit log is disabled
+func (s *Server) previousValues(after map[string]any) map[string]any {
+	if s.audit == nil {
+		return nil
+	}
+	current := map[string]func() any{
+		"mode":               func() any { return s.climate.GetMode() },
+		"target_temperature": func() any { return s.climate.GetTargetTemperature() },
+		"hw_target_temp":     func() any { return s.climate.GetHWTargetTemp() },
+		"inside_temp":        func() any { return s.climate.GetInsideTemp() },
+		"outside_temp":       func() any { return s.climate.GetOutsideTemp() },
+		"heating_end_time":   func() any { return s.climate.GetStat().HeatingEndTime },
+		"force_heating":      func() any { return s.climate.GetStat().HeatingEndTime != "" }, // heating was running
+	}
+	old := map[string]any{}
+	for key := range after {
+		if value, ok := current[key]; ok {
+			old[key] = value()
+		}
+	}
+	return old
+}
+
+// handleAudit serves /audit?from=&to=&principal=&endpoint=
+// from and to are RFC3339 or unix time, default last 7 days
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if s.audit == nil {
+		http.Error(w, "Audit log is disabled", http.StatusNotFound)
+		return
+	}
+	query := r.URL.Query()
+	now := time.Now()
+	from, err := parseTime(query.Get("from"), now.Add(-7*24*time.Hour))
+	if err != nil {
+		http.Error(w, "Invalid from", http.StatusBadRequest)
+		return
+	}
+	to, err := parseTime(query.Get("to"), now)
+	if err != nil {
+		http.Error(w, "Invalid to", http.StatusBadRequest)
+		return
+	}
+	entries, err := s.audit.Query(audit.Filter{
+		From:      from,
+		To:        to,
+		Principal: query.Get("principal"),
+		Endpoint:  query.Get("endpoint"),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to query audit log")
+		http.Error(w, "Failed to query audit log", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
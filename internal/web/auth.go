@@ -0,0 +1,187 @@
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+const AUTH_TOKEN = "token"
+const AUTH_BASIC = "basic"
+const AUTH_CLIENT_CERT = "client_cert"
+const AUTH_NONE = "none" // auth is disabled
+
+type principalKey struct{}
+
+// Principal is authenticated caller of a request
+type Principal struct {
+	Name   string
+	Scope  string
+	Method string
+}
+
+// allows reports if principal scope covers required scope
+func (p Principal) allows(scope string) bool {
+	return p.Scope == config.SCOPE_CONTROL || p.Scope == scope
+}
+
+type Authenticator struct {
+	config  config.Auth
+	enabled bool
+}
+
+func NewAuthenticator(cfg config.Auth) *Authenticator {
+	for _, t := range cfg.Tokens {
+		if !validScope(t.Scope) {
+			log.Warn().Msgf("Token %s has invalid scope %q, using %s", t.Name, t.Scope, config.SCOPE_READ)
+		}
+	}
+	for _, u := range cfg.Users {
+		if !validScope(u.Scope) {
+			log.Warn().Msgf("User %s has invalid scope %q, using %s", u.Username, u.Scope, config.SCOPE_READ)
+		}
+	}
+	return &Authenticator{
+		config:  cfg,
+		enabled: len(cfg.Tokens) > 0 || len(cfg.Users) > 0 || cfg.ClientCertScope != "",
+	}
+}
+
+func validScope(scope string) bool {
+	return scope == config.SCOPE_READ || scope == config.SCOPE_CONTROL
+}
+
+// scope falls back to read only for unknown scopes
+func scope(value string) string {
+	if value == config.SCOPE_CONTROL {
+		return value
+	}
+	return config.SCOPE_READ
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// Authenticate returns principal of request credentials
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, bool) {
+	if !a.enabled {
+		return Principal{Name: "anonymous", Scope: config.SCOPE_CONTROL, Method: AUTH_NONE}, true
+	}
+
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && a.config.ClientCertScope != "" {
+		cert := r.TLS.VerifiedChains[0][0]
+		return Principal{Name: cert.Subject.CommonName, Scope: scope(a.config.ClientCertScope), Method: AUTH_CLIENT_CERT}, true
+	}
+
+	token := ""
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token = strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	} else if value := r.URL.Query().Get("token"); value != "" && queryTokenAllowed(r) {
+		token = value // EventSource in browsers can't set headers
+	}
+	if token != "" {
+		for _, t := range a.config.Tokens {
+			if t.Token != "" && equal(token, t.Token) {
+				return Principal{Name: t.Name, Scope: scope(t.Scope), Method: AUTH_TOKEN}, true
+			}
+		}
+		return Principal{}, false
+	}
+
+	if username, password, ok := r.BasicAuth(); ok {
+		for _, u := range a.config.Users {
+			if u.Username != username {
+				continue
+			}
+			if u.PasswordSHA256 != "" {
+				sum := sha256.Sum256([]byte(password))
+				if equal(hex.EncodeToString(sum[:]), strings.ToLower(u.PasswordSHA256)) {
+					return Principal{Name: username, Scope: scope(u.Scope), Method: AUTH_BASIC}, true
+				}
+			} else if u.Password != "" && equal(password, u.Password) {
+				return Principal{Name: username, Scope: scope(u.Scope), Method: AUTH_BASIC}, true
+			}
+		}
+	}
+	return Principal{}, false
+}
+
+// queryTokenAllowed limits token in URL to event streams, URLs end up in
+// access logs, browser history and Referer headers
+func queryTokenAllowed(r *http.Request) bool {
+	return r.URL.Path == "/events" || r.URL.Path == API_PREFIX+"/events"
+}
+
+// crossSite reports requests sent by pages of other sites, browsers attach
+// cached basic auth credentials to them
+func crossSite(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" {
+		return site != "same-origin" && site != "none"
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false // not sent by a browser
+	}
+	u, err := url.Parse(origin)
+	return err != nil || u.Host != r.Host
+}
+
+// ErrorWriter writes rejection of request with status and reason
+type ErrorWriter func(w http.ResponseWriter, status int, message string)
+
+// TextError writes status text as plain text response
+func TextError(w http.ResponseWriter, status int, message string) {
+	http.Error(w, http.StatusText(status), status)
+}
+
+// Require wraps handler with authentication and scope check, failures are written by reject
+func (a *Authenticator) Require(requiredScope string, reject ErrorWriter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := a.Authenticate(r)
+		if !ok {
+			if len(a.config.Users) > 0 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="ebus-climate"`)
+			}
+			log.Warn().Msgf("Unauthorized request to %s from %s", r.URL.Path, r.RemoteAddr)
+			reject(w, http.StatusUnauthorized, "missing or invalid credentials")
+			return
+		}
+		if !principal.allows(requiredScope) {
+			log.Warn().Msgf("%s (%s) is not allowed to %s %s", principal.Name, principal.Scope, requiredScope, r.URL.Path)
+			reject(w, http.StatusForbidden, "scope "+requiredScope+" is required")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), principal)))
+	})
+}
+
+func withPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns principal of authenticated request
+func PrincipalFrom(r *http.Request) (Principal, bool) {
+	principal, ok := r.Context().Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// handleCheck validates credentials, scope of caller is returned in X-Auth-Scope header
+func (s *Server) handleCheck(w http.ResponseWriter, r *http.Request) {
+	principal, ok := PrincipalFrom(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("X-Auth-Name", principal.Name)
+	w.Header().Set("X-Auth-Scope", principal.Scope)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
@@ -0,0 +1,111 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ksimuk/ebus-climate/internal/config"
+)
+
+func testAuthenticator() *Authenticator {
+	sum := sha256.Sum256([]byte("hashed"))
+	return NewAuthenticator(config.Auth{
+		Tokens: []config.AuthToken{
+			{Name: "dashboard", Token: "read-token", Scope: config.SCOPE_READ},
+			{Name: "automation", Token: "control-token", Scope: config.SCOPE_CONTROL},
+		},
+		Users: []config.AuthUser{
+			{Username: "admin", Password: "secret", Scope: config.SCOPE_CONTROL},
+			{Username: "guest", PasswordSHA256: hex.EncodeToString(sum[:]), Scope: "bogus"},
+		},
+	})
+}
+
+func TestAuthRequire(t *testing.T) {
+	auth := testAuthenticator()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, found := PrincipalFrom(r); !found {
+			t.Error("principal missing from request context")
+		}
+	})
+	cases := []struct {
+		name   string
+		scope  string
+		setup  func(r *http.Request)
+		status int
+	}{
+		{"no credentials", config.SCOPE_READ, func(r *http.Request) {}, http.StatusUnauthorized},
+		{"read token", config.SCOPE_READ, func(r *http.Request) { r.Header.Set("Authorization", "Bearer read-token") }, http.StatusOK},
+		{"read token on control", config.SCOPE_CONTROL, func(r *http.Request) { r.Header.Set("Authorization", "Bearer read-token") }, http.StatusForbidden},
+		{"control token", config.SCOPE_CONTROL, func(r *http.Request) { r.Header.Set("Authorization", "Bearer control-token") }, http.StatusOK},
+		{"query token", config.SCOPE_READ, func(r *http.Request) { r.URL.RawQuery = "token=read-token" }, http.StatusUnauthorized},
+		{"query token on events", config.SCOPE_READ, func(r *http.Request) { r.URL.Path = "/events"; r.URL.RawQuery = "token=read-token" }, http.StatusOK},
+		{"wrong token", config.SCOPE_READ, func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized},
+		{"basic", config.SCOPE_CONTROL, func(r *http.Request) { r.SetBasicAuth("admin", "secret") }, http.StatusOK},
+		{"basic wrong password", config.SCOPE_READ, func(r *http.Request) { r.SetBasicAuth("admin", "wrong") }, http.StatusUnauthorized},
+		{"hashed password", config.SCOPE_READ, func(r *http.Request) { r.SetBasicAuth("guest", "hashed") }, http.StatusOK},
+		{"invalid scope is read only", config.SCOPE_CONTROL, func(r *http.Request) { r.SetBasicAuth("guest", "hashed") }, http.StatusForbidden},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/get", nil)
+		c.setup(r)
+		w := httptest.NewRecorder()
+		auth.Require(c.scope, TextError, ok).ServeHTTP(w, r)
+		if w.Code != c.status {
+			t.Errorf("%s: expected status %d, got %d", c.name, c.status, w.Code)
+		}
+	}
+}
+
+func TestAuthDisabled(t *testing.T) {
+	auth := NewAuthenticator(config.Auth{})
+	principal, ok := auth.Authenticate(httptest.NewRequest(http.MethodGet, "/set", nil))
+	if !ok || !principal.allows(config.SCOPE_CONTROL) {
+		t.Errorf("expected open access when auth is not configured, got %+v", principal)
+	}
+}
+
+func TestControlRequests(t *testing.T) {
+	c := &stubClimate{target: 20}
+	cfg := config.Config{}
+	cfg.Auth.Users = []config.AuthUser{{Username: "admin", Password: "secret", Scope: config.SCOPE_CONTROL}}
+	s := NewServer(cfg, c, nil)
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		header map[string]string
+		status int
+	}{
+		{"get override", http.MethodGet, "/override?inside_temp=19", nil, http.StatusOK},
+		{"cross site get", http.MethodGet, "/force_heating?duration=10", map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+		{"post", http.MethodPost, "/set", nil, http.StatusOK},
+		{"same origin", http.MethodPost, "/set", map[string]string{"Origin": "http://example.com"}, http.StatusOK},
+		{"other origin", http.MethodPost, "/set", map[string]string{"Origin": "http://evil.test"}, http.StatusForbidden},
+		{"cross site fetch", http.MethodPost, "/set", map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+		{"api other origin", http.MethodPatch, "/api/v1/settings", map[string]string{"Origin": "http://evil.test"}, http.StatusForbidden},
+	}
+	for _, cs := range cases {
+		r := httptest.NewRequest(cs.method, "http://example.com"+cs.path, strings.NewReader(`{"target_temperature": 21}`))
+		r.SetBasicAuth("admin", "secret")
+		for key, value := range cs.header {
+			r.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		s.mux.ServeHTTP(w, r)
+		if w.Code != cs.status {
+			t.Errorf("%s: expected status %d, got %d", cs.name, cs.status, w.Code)
+		}
+	}
+	if c.target != 21 || c.inside != 19 {
+		t.Errorf("expected same site requests to apply, got target %f inside %f", c.target, c.inside)
+	}
+	if c.runFor != 0 {
+		t.Errorf("expected cross site request to be rejected, got run for %d", c.runFor)
+	}
+}
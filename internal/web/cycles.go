@@ -0,0 +1,48 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/climate"
+	"github.com/rs/zerolog/log"
+)
+
+// handleCycles serves /cycles?from=&to=&trigger=
+// from and to are RFC3339 or unix time, default last 7 days
+func (s *Server) handleCycles(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	now := time.Now()
+	from, err := parseTime(query.Get("from"), now.Add(-7*24*time.Hour))
+	if err != nil {
+		http.Error(w, "Invalid from", http.StatusBadRequest)
+		return
+	}
+	to, err := parseTime(query.Get("to"), now)
+	if err != nil {
+		http.Error(w, "Invalid to", http.StatusBadRequest)
+		return
+	}
+
+	cycles, err := s.climate.GetCycles(from, to)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to query cycles")
+		http.Error(w, "Failed to query cycles", http.StatusInternalServerError)
+		return
+	}
+	if trigger := query.Get("trigger"); trigger != "" {
+		filtered := []climate.Cycle{}
+		for _, cycle := range cycles {
+			if cycle.Trigger == trigger {
+				filtered = append(filtered, cycle)
+			}
+		}
+		cycles = filtered
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(cycles); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
@@ -0,0 +1,19 @@
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed dashboard
+var dashboardFS embed.FS
+
+// dashboardHandler serves embedded single page dashboard on /
+func dashboardHandler() http.Handler {
+	static, err := fs.Sub(dashboardFS, "dashboard")
+	if err != nil {
+		panic(err) // embedded directory always exists
+	}
+	return http.FileServer(http.FS(static))
+}
@@ -0,0 +1,227 @@
+// Dashboard for ebus-climate, state comes from /get and is refreshed by /events
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+let state = null;
+let editing = false; // do not overwrite controls while user edits them
+// token auth uses /#token=..., fragment is not sent to server, basic auth is handled by browser
+const TOKEN = new URLSearchParams(location.hash.slice(1)).get("token");
+
+// request sends token in Authorization header
+function request(path, options = {}) {
+  if (TOKEN) options.headers = { ...options.headers, Authorization: "Bearer " + TOKEN };
+  return fetch(path, options);
+}
+
+// events adds token to URL, EventSource can't set headers
+function events() {
+  return TOKEN ? "events?token=" + encodeURIComponent(TOKEN) : "events";
+}
+
+function fmt(value, unit, digits = 1) {
+  if (value === undefined || value === null || Number.isNaN(value)) return "–";
+  return Number(value).toFixed(digits) + unit;
+}
+
+function render() {
+  if (!state) return;
+  const stat = state.stat || {};
+  $("name").textContent = state.boiler.name || "ebus-climate";
+  $("inside_temp").textContent = fmt(state.inside_temp, "°");
+  $("target_temperature").textContent = fmt(state.target_temperature, "°");
+  $("outside_temp").textContent = fmt(state.outside_temp, "°");
+  $("flow_return").textContent = fmt(state.flow_temp, "°", 0) + " / " + fmt(state.return_temp, "°", 0);
+  $("water_pressure").textContent = fmt(stat.water_pressure, " bar");
+  $("hot_water").textContent = fmt(state.hw_target_temp, "°", 0) + (stat.hw_mode ? " " + stat.hw_mode : "");
+
+  const balance = state.heat_loss_balance;
+  $("heat_loss_balance").textContent = fmt(balance, " Wh", 0);
+  $("heat_loss").textContent = "loss " + fmt(stat.current_heat_loss, " W", 0);
+  // balance bar: centre is zero, full width is one hour at boiler power
+  const scale = state.power || 10000;
+  const width = Math.max(0, Math.min(100, 50 + (balance / scale) * 50));
+  $("balance_bar").style.width = width + "%";
+
+  const burner = $("burner");
+  burner.textContent = state.gas_active ? "heating" : state.mode === "off" ? "off" : "idle";
+  burner.className = state.gas_active ? "heating" : "";
+
+  if (!editing) {
+    $("mode").value = state.mode;
+    $("target").value = state.target_temperature;
+    $("hw_target").value = state.hw_target_temp;
+  }
+  updateCountdown();
+}
+
+function updateCountdown() {
+  const el = $("countdown");
+  const end = state && state.stat && state.stat.heating_end_time;
+  if (!state || !state.gas_active || !end) {
+    el.textContent = "";
+    return;
+  }
+  const seconds = Math.max(0, Math.round((new Date(end) - Date.now()) / 1000));
+  const minutes = Math.floor(seconds / 60);
+  el.textContent = "ends in " + minutes + ":" + String(seconds % 60).padStart(2, "0");
+}
+
+async function refresh() {
+  try {
+    const response = await request("get");
+    if (!response.ok) throw new Error(response.statusText);
+    state = await response.json();
+    render();
+  } catch (err) {
+    message("Failed to load state: " + err.message);
+  }
+}
+
+function message(text) {
+  $("message").textContent = text;
+}
+
+function connectEvents() {
+  const badge = $("connection");
+  const source = new EventSource(events());
+  source.addEventListener("snapshot", (e) => {
+    state = JSON.parse(e.data);
+    render();
+  });
+  for (const type of ["boiler", "loss", "heating_started", "heating_stopped", "settings"]) {
+    // events carry diffs only, reload full state to keep derived values consistent
+    source.addEventListener(type, refresh);
+  }
+  source.onopen = () => {
+    badge.textContent = "live";
+    badge.className = "badge live";
+  };
+  source.onerror = () => {
+    badge.textContent = "reconnecting";
+    badge.className = "badge";
+  };
+}
+
+// apply sends settings to /api/v1/settings, limits are checked by server and 0 clears hot water target
+async function apply() {
+  const hw = $("hw_target").value;
+  const body = {
+    mode: $("mode").value,
+    target_temperature: parseFloat($("target").value),
+    hw_target_temp: hw === "" ? 0 : parseInt(hw, 10),
+  };
+  try {
+    const response = await request("api/v1/settings", {
+      method: "PATCH",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(body),
+    });
+    if (!response.ok) {
+      const failure = await response.json().catch(() => ({}));
+      throw new Error((failure.error && failure.error.message) || response.statusText);
+    }
+    message("Applied");
+    editing = false;
+    refresh();
+  } catch (err) {
+    message("Failed to apply: " + err.message);
+  }
+}
+
+async function force() {
+  const minutes = parseInt($("force_minutes").value, 10);
+  try {
+    const response = await request("force_heating?duration=" + minutes, { method: "POST" });
+    if (!response.ok) throw new Error(await response.text());
+    message("Heating for " + minutes + " minutes");
+    refresh();
+  } catch (err) {
+    message("Failed to force heating: " + err.message);
+  }
+}
+
+const COLORS = ["#ff8a3d", "#4fa3ff", "#4caf50", "#c77dff", "#ffd166"];
+
+function drawChart(svg, legend, records, fields) {
+  const width = 600;
+  const height = 200;
+  svg.innerHTML = "";
+  legend.innerHTML = "";
+  if (records.length < 2) return;
+  const times = records.map((r) => new Date(r.t).getTime());
+  const t0 = times[0];
+  const t1 = times[times.length - 1];
+  let min = Infinity;
+  let max = -Infinity;
+  for (const r of records) {
+    for (const f of fields) {
+      const v = r.v[f];
+      if (v === undefined) continue;
+      min = Math.min(min, v);
+      max = Math.max(max, v);
+    }
+  }
+  if (!Number.isFinite(min)) return;
+  if (max === min) max = min + 1;
+  const x = (t) => ((t - t0) / (t1 - t0 || 1)) * width;
+  const y = (v) => height - 10 - ((v - min) / (max - min)) * (height - 20);
+
+  const ns = "http://www.w3.org/2000/svg";
+  fields.forEach((field, i) => {
+    const points = [];
+    records.forEach((r, j) => {
+      if (r.v[field] !== undefined) points.push(x(times[j]).toFixed(1) + "," + y(r.v[field]).toFixed(1));
+    });
+    const line = document.createElementNS(ns, "polyline");
+    line.setAttribute("points", points.join(" "));
+    line.setAttribute("fill", "none");
+    line.setAttribute("stroke", COLORS[i % COLORS.length]);
+    line.setAttribute("stroke-width", "1.5");
+    line.setAttribute("vector-effect", "non-scaling-stroke");
+    svg.appendChild(line);
+    legend.insertAdjacentHTML("beforeend", '<span><i style="background:' + COLORS[i % COLORS.length] + '"></i>' + field + "</span>");
+  });
+  for (const [value, pos] of [[max, 12], [min, height - 2]]) {
+    const label = document.createElementNS(ns, "text");
+    label.setAttribute("x", 4);
+    label.setAttribute("y", pos);
+    label.textContent = value.toFixed(1);
+    svg.appendChild(label);
+  }
+}
+
+async function loadHistory() {
+  const hours = parseInt($("range").value, 10);
+  const from = Math.floor(Date.now() / 1000) - hours * 3600;
+  const step = hours > 24 ? "30m" : hours > 6 ? "5m" : "1m";
+  const fields = "inside_temp,outside_temp,target_temp,flow_temp,heat_loss_balance";
+  try {
+    const response = await request("history?from=" + from + "&step=" + step + "&fields=" + fields);
+    if (!response.ok) throw new Error(response.statusText);
+    const records = (await response.json()) || [];
+    drawChart($("chart_temps"), $("legend_temps"), records, ["inside_temp", "outside_temp", "target_temp", "flow_temp"]);
+    drawChart($("chart_balance"), $("legend_balance"), records, ["heat_loss_balance"]);
+  } catch (err) {
+    message("Failed to load history: " + err.message);
+  }
+}
+
+for (const button of document.querySelectorAll("button[data-step]")) {
+  button.addEventListener("click", () => {
+    const input = $(button.dataset.for);
+    input.value = (parseFloat(input.value || 0) + parseFloat(button.dataset.step)).toString();
+    editing = true;
+  });
+}
+for (const id of ["mode", "target", "hw_target"]) {
+  $(id).addEventListener("input", () => (editing = true));
+}
+$("apply").addEventListener("click", apply);
+$("force").addEventListener("click", force);
+$("range").addEventListener("change", loadHistory);
+
+refresh();
+connectEvents();
+loadHistory();
+setInterval(updateCountdown, 1000);
+setInterval(loadHistory, 5 * 60 * 1000);
@@ -0,0 +1,84 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/energy"
+	"github.com/rs/zerolog/log"
+)
+
+// energyReporter is implemented by climate which keeps energy ledger
+type energyReporter interface {
+	GetEnergy(period string, from, to time.Time) energy.Report // period is day or month
+	GetDegreeDays(period string, from, to time.Time) energy.DegreeDayReport
+}
+
+// parsePeriod reads period, from and to, defaults are last 30 days for day and last year for month
+func parsePeriod(r *http.Request) (string, time.Time, time.Time, error) {
+	query := r.URL.Query()
+	period := query.Get("period")
+	if period == "" {
+		period = energy.PERIOD_DAY
+	}
+	if period != energy.PERIOD_DAY && period != energy.PERIOD_MONTH {
+		return "", time.Time{}, time.Time{}, errors.New("invalid period, expected day or month")
+	}
+	now := time.Now()
+	defaultFrom := now.AddDate(0, 0, -30)
+	if period == energy.PERIOD_MONTH {
+		defaultFrom = now.AddDate(-1, 0, 0)
+	}
+	from, err := parseTime(query.Get("from"), defaultFrom)
+	if err != nil {
+		return "", time.Time{}, time.Time{}, errors.New("invalid from")
+	}
+	// include today by default
+	to, err := parseTime(query.Get("to"), now.AddDate(0, 0, 1))
+	if err != nil {
+		return "", time.Time{}, time.Time{}, errors.New("invalid to")
+	}
+	return period, from, to, nil
+}
+
+// handleEnergy serves /energy?period=day|month&from=&to=
+// from and to are RFC3339 or unix time
+func (s *Server) handleEnergy(w http.ResponseWriter, r *http.Request) {
+	reporter, ok := s.climate.(energyReporter)
+	if !ok {
+		http.Error(w, "Energy ledger not available", http.StatusNotFound)
+		return
+	}
+	period, from, to, err := parsePeriod(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(reporter.GetEnergy(period, from, to)); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// handleDegreeDays serves /degree_days?period=day|month&from=&to=
+// month period is the monthly efficiency summary compared with previous month
+func (s *Server) handleDegreeDays(w http.ResponseWriter, r *http.Request) {
+	reporter, ok := s.climate.(energyReporter)
+	if !ok {
+		http.Error(w, "Energy ledger not available", http.StatusNotFound)
+		return
+	}
+	period, from, to, err := parsePeriod(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(reporter.GetDegreeDays(period, from, to)); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
@@ -0,0 +1,78 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const EVENTS_KEEPALIVE = 30 * time.Second
+
+// handleEvents streams state changes as server-sent events on /events?types=boiler,loss
+// first event is a snapshot with /get payload
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	types := map[string]bool{}
+	if value := r.URL.Query().Get("types"); value != "" {
+		for _, t := range strings.Split(value, ",") {
+			types[strings.TrimSpace(t)] = true
+		}
+	}
+
+	events, unsubscribe := s.climate.Events().Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeEvent(w, "snapshot", s.getState()); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(EVENTS_KEEPALIVE)
+	defer keepalive.Stop()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if len(types) > 0 && !types[event.Type] {
+				continue
+			}
+			if err := writeEvent(w, event.Type, event); err != nil {
+				return
+			}
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode event")
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, payload)
+	return err
+}
@@ -0,0 +1,40 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/ksimuk/ebus-climate/internal/climate"
+)
+
+type healthResponse struct {
+	Status     string                    `json:"status"`
+	Components []climate.ComponentHealth `json:"components"`
+}
+
+// handleHealthz is liveness, fails only when control loop is stuck
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	health := s.climate.Health()
+	cycler, _ := health.Component(climate.COMPONENT_CYCLER)
+	status := http.StatusOK
+	if cycler.Status == climate.HEALTH_FAILED {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, healthResponse{Status: cycler.Status, Components: []climate.ComponentHealth{cycler}})
+}
+
+// handleReadyz is readiness, fails when any subsystem failed or server is shutting down
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	health := s.climate.Health()
+	response := healthResponse{Status: health.Status(), Components: health.Components}
+	if s.draining.Load() {
+		response.Status = climate.HEALTH_FAILED
+		response.Components = append(response.Components, climate.ComponentHealth{
+			Name: "web", Status: climate.HEALTH_FAILED, Message: "shutting down",
+		})
+	}
+	status := http.StatusOK
+	if response.Status == climate.HEALTH_FAILED {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, response)
+}
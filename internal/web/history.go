@@ -0,0 +1,154 @@
+package web
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/history"
+	"github.com/ksimuk/ebus-climate/internal/vailant"
+	"github.com/rs/zerolog/log"
+)
+
+const HISTORY_INTERVAL = time.Minute
+
+// sample collects readings and control decisions for history
+func (s *Server) sample() history.Record {
+	stat := s.climate.GetStat()
+	values := map[string]float64{
+		"inside_temp":       s.climate.GetInsideTemp(),
+		"outside_temp":      s.climate.GetOutsideTemp(),
+		"target_temp":       s.climate.GetTargetTemperature(),
+		"hw_target_temp":    float64(s.climate.GetHWTargetTemp()),
+		"flow_temp":         s.climate.GetFlowTemp(),
+		"return_temp":       s.climate.GetReturnTemp(),
+		"heat_loss_balance": s.climate.GetHeatLossBalance(),
+		"heat_loss":         stat.CurrentHeatLoss,
+		"loss_wind":         stat.LossWind,
+		"loss_solar":        stat.LossSolar,
+		"water_pressure":    stat.WaterPressure,
+		"heating_active":    boolValue(s.climate.IsGasActive()),
+		"heating_mode":      boolValue(s.climate.GetMode() == vailant.MODE_HEATING),
+		"runtime":           float64(stat.Runtime),
+		"consumption":       s.climate.GetConsumption(),
+		"degraded":          boolValue(stat.DegradedMode != ""),
+	}
+	if stat.HwStorageTemp >= 0 {
+		values["hw_storage_temp"] = stat.HwStorageTemp
+	}
+	if stat.ForecastTemp != 0 {
+		values["forecast_temp"] = stat.ForecastTemp
+	}
+	return history.Record{Time: time.Now(), Values: values}
+}
+
+func (s *Server) recordHistory() {
+	ticker := time.NewTicker(HISTORY_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.history.Append(s.sample()); err != nil {
+				log.Error().Err(err).Msg("Failed to record history")
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func parseTime(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// handleHistory serves /history?from=&to=&fields=&step=&format=
+// from and to are RFC3339 or unix time, default last 24 hours,
+// step is a duration like 15m, format is json (default) or csv
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if s.history == nil {
+		http.Error(w, "History is disabled", http.StatusNotFound)
+		return
+	}
+	query := r.URL.Query()
+	now := time.Now()
+	from, err := parseTime(query.Get("from"), now.Add(-24*time.Hour))
+	if err != nil {
+		http.Error(w, "Invalid from", http.StatusBadRequest)
+		return
+	}
+	to, err := parseTime(query.Get("to"), now)
+	if err != nil {
+		http.Error(w, "Invalid to", http.StatusBadRequest)
+		return
+	}
+	step := time.Duration(0)
+	if value := query.Get("step"); value != "" {
+		step, err = time.ParseDuration(value)
+		if err != nil || step < 0 {
+			http.Error(w, "Invalid step", http.StatusBadRequest)
+			return
+		}
+	}
+	fields := []string{}
+	if value := query.Get("fields"); value != "" {
+		fields = strings.Split(value, ",")
+	}
+
+	records, err := s.history.Query(from, to, fields, step)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to query history")
+		http.Error(w, "Failed to query history", http.StatusInternalServerError)
+		return
+	}
+
+	if query.Get("format") == "csv" {
+		writeHistoryCSV(w, records, fields)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(records); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func writeHistoryCSV(w http.ResponseWriter, records []history.Record, fields []string) {
+	if len(fields) == 0 {
+		known := map[string]bool{}
+		for _, record := range records {
+			for field := range record.Values {
+				if !known[field] {
+					known[field] = true
+					fields = append(fields, field)
+				}
+			}
+		}
+		sort.Strings(fields)
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	writer := csv.NewWriter(w)
+	writer.Write(append([]string{"time"}, fields...))
+	for _, record := range records {
+		row := []string{record.Time.Format(time.RFC3339)}
+		for _, field := range fields {
+			value, ok := record.Values[field]
+			if !ok {
+				row = append(row, "")
+				continue
+			}
+			row = append(row, strconv.FormatFloat(value, 'f', -1, 64))
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+}
@@ -0,0 +1,139 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+const UNIX_PREFIX = "unix:"
+const CERT_CHECK_INTERVAL = 10 * time.Second // certificate files are checked for change at most this often
+
+// listen opens TCP or unix socket listener, wrapped in TLS when certificate is configured
+func listen(cfg *config.Config) (net.Listener, error) {
+	var listener net.Listener
+	var err error
+	if path, ok := strings.CutPrefix(cfg.Listen, UNIX_PREFIX); ok {
+		// remove stale socket from previous run
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		listener, err = net.Listen("unix", path)
+	} else {
+		listener, err = net.Listen("tcp", net.JoinHostPort(cfg.Listen, fmt.Sprint(cfg.WebPort)))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.TLS.Cert == "" {
+		return listener, nil
+	}
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return tls.NewListener(listener, tlsConfig), nil
+}
+
+func newTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	reloader, err := newCertReloader(cfg.TLS.Cert, cfg.TLS.Key)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	if cfg.TLS.ClientCA != "" {
+		pem, err := os.ReadFile(cfg.TLS.ClientCA)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.TLS.ClientCA)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		if cfg.TLS.RequireClientCert {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return tlsConfig, nil
+}
+
+// certReloader reloads certificate when certificate or key file changes
+type certReloader struct {
+	certPath string
+	keyPath  string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	lastCheck time.Time
+}
+
+func newCertReloader(certPath, keyPath string) (*certReloader, error) {
+	r := &certReloader{certPath: certPath, keyPath: keyPath}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// latestModTime returns newest modification time of certificate and key
+func (r *certReloader) latestModTime() (time.Time, error) {
+	latest := time.Time{}
+	for _, path := range []string{r.certPath, r.keyPath} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (r *certReloader) load() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return err
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.lastCheck) >= CERT_CHECK_INTERVAL {
+		r.lastCheck = time.Now()
+		if modTime, err := r.latestModTime(); err == nil && !modTime.Equal(r.modTime) {
+			// keep serving previous certificate if new one is incomplete
+			if err := r.load(); err != nil {
+				log.Error().Err(err).Msg("Failed to reload TLS certificate")
+			} else {
+				log.Info().Msgf("Reloaded TLS certificate %s", r.certPath)
+			}
+		}
+	}
+	return r.cert, nil
+}
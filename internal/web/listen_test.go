@@ -0,0 +1,82 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestCert(t *testing.T, certPath, keyPath, name string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+}
+
+func commonName(t *testing.T, r *certReloader) string {
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Subject.CommonName
+}
+
+func TestCertReload(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	writeTestCert(t, certPath, keyPath, "first")
+
+	r, err := newCertReloader(certPath, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := commonName(t, r); name != "first" {
+		t.Fatalf("expected first certificate, got %s", name)
+	}
+
+	writeTestCert(t, certPath, keyPath, "second")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certPath, future, future)
+	r.lastCheck = time.Time{} // skip check interval
+	if name := commonName(t, r); name != "second" {
+		t.Errorf("expected reloaded certificate, got %s", name)
+	}
+
+	// broken key keeps previous certificate
+	os.WriteFile(keyPath, []byte("broken"), 0600)
+	future = future.Add(time.Minute)
+	os.Chtimes(keyPath, future, future)
+	r.lastCheck = time.Time{}
+	if name := commonName(t, r); name != "second" {
+		t.Errorf("expected previous certificate after failed reload, got %s", name)
+	}
+}
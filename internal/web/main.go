@@ -1,20 +1,41 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/ksimuk/ebus-climate/internal/audit"
 	"github.com/ksimuk/ebus-climate/internal/climate"
 	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/ksimuk/ebus-climate/internal/history"
 	"github.com/ksimuk/ebus-climate/internal/vailant"
 	"github.com/rs/zerolog/log"
 )
 
+const SHUTDOWN_TIMEOUT = 10 * time.Second
+
 type Server struct {
 	climate climate.Climate
 	config  config.Config
+	history *history.Store
+	audit   *audit.Log
+
+	settingsMutex *sync.Mutex // serializes settings updates
+
+	mux        *http.ServeMux
+	httpServer *http.Server
+	listener   net.Listener
+	draining   atomic.Bool   // commands are rejected during shutdown
+	done       chan struct{} // closed on shutdown, ends event streams and history recording
+	shutdown   sync.Once
 }
 
 type Set struct {
@@ -54,35 +75,151 @@ type Get struct {
 	Stat climate.Stat `json:"stat"`
 }
 
-func GetServer(config config.Config) Server {
+func GetServer(config config.Config) *Server {
 	climate := vailant.New(&config) // TODO: make load boiler type from config when change boiler
-	server := Server{
+	store, err := history.New(&config)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to open history store")
+	}
+	return NewServer(config, climate, store)
+}
+
+// NewServer creates server for climate, history store is optional
+func NewServer(config config.Config, climate climate.Climate, store *history.Store) *Server {
+	s := &Server{
 		config:  config,
 		climate: climate,
+		history: store,
+		audit:   audit.New(config.AuditLog),
+
+		settingsMutex: &sync.Mutex{},
+		mux:           http.NewServeMux(),
+		done:          make(chan struct{}),
+	}
+	s.routes()
+	s.httpServer = &http.Server{
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
 	}
-	return server
+	return s
+}
+
+func (s *Server) Climate() climate.Climate {
+	return s.climate
+}
+
+// Audit returns log of control actions, nil if disabled
+func (s *Server) Audit() *audit.Log {
+	return s.audit
 }
 
-func (s *Server) Start() {
-	log.Info().Msgf("Starting web server on port %d...", s.config.WebPort)
-	http.HandleFunc("/set", s.handleSet)
-	http.HandleFunc("/get", s.handleGet)
+func (s *Server) routes() {
+	auth := NewAuthenticator(s.config.Auth)
+	read := func(path string, handler http.HandlerFunc) {
+		s.mux.Handle(path, auth.Require(config.SCOPE_READ, TextError, handler))
+	}
+	control := func(path string, handler http.HandlerFunc) {
+		s.mux.Handle(path, auth.Require(config.SCOPE_CONTROL, TextError, s.commands(handler)))
+	}
+
+	control("/set", s.handleSet)
+	read("/get", s.handleGet)
+	read("/metrics", s.handleMetrics)
+	read("/history", s.handleHistory)
+	read("/cycles", s.handleCycles)
+	read("/energy", s.handleEnergy)
+	read("/degree_days", s.handleDegreeDays)
+	read("/events", s.handleEvents)
+	// dashboard is static, its data is fetched with credentials
+	s.mux.Handle("/", dashboardHandler())
+	s.mux.Handle(API_PREFIX+"/", s.apiHandler(auth))
 
 	// Override endpoints for temperature sensors
-	http.HandleFunc("/override", s.handleOverride)
-	http.HandleFunc("/force_heating", s.handleForceHeating)
-	http.HandleFunc("/check", func(w http.ResponseWriter, r *http.Request) {
-		// todo authentication check
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
+	control("/override", s.handleOverride)
+	control("/force_heating", s.handleForceHeating)
+	read("/check", s.handleCheck)
+	read("/audit", s.handleAudit)
+
+	// health checks are open for systemd and container probes
+	s.mux.HandleFunc("/healthz", s.handleHealthz)
+	s.mux.HandleFunc("/readyz", s.handleReadyz)
 
-	http.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+	s.mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
+}
+
+// commands rejects state changing requests sent by pages of other sites and those sent once shutdown started,
+// any method is accepted as sensor scripts push readings to legacy endpoints with GET
+func (s *Server) commands(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if crossSite(r) {
+			log.Warn().Msgf("Rejected cross-site request to %s from %s", r.URL.Path, r.Header.Get("Origin"))
+			http.Error(w, "Cross-site request rejected", http.StatusForbidden)
+			return
+		}
+		if s.draining.Load() {
+			http.Error(w, "Shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Listen opens configured listener, port 0 picks a free port
+func (s *Server) Listen() error {
+	listener, err := listen(&s.config)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	log.Info().Msgf("Listening on %s (TLS: %t)", listener.Addr(), s.config.TLS.Cert != "")
+	return nil
+}
+
+// Addr returns address of listener
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Start listens if needed and serves until Shutdown, returns nil after graceful shutdown
+func (s *Server) Start() error {
+	log.Info().Msg("Starting web server...")
+	if s.listener == nil {
+		if err := s.Listen(); err != nil {
+			return err
+		}
+	}
+	if s.history != nil {
+		go s.recordHistory()
+	}
+	if err := s.httpServer.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
 
-	log.Fatal().Err(http.ListenAndServe(fmt.Sprintf(":%d", s.config.WebPort), nil)).Msg("Web server stopped")
+// Shutdown stops accepting commands, drains in-flight requests,
+// then ends current cycle and flushes climate state and history
+func (s *Server) Shutdown(ctx context.Context) error {
+	var err error
+	s.shutdown.Do(func() {
+		s.draining.Store(true)
+		close(s.done)
+		if err = s.httpServer.Shutdown(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to drain web server")
+		}
+		s.climate.Shutdown()
+		if s.history != nil {
+			s.history.Close()
+		}
+		log.Info().Msg("Web server stopped")
+	})
+	return err
 }
 
 func (s *Server) handleForceHeating(w http.ResponseWriter, r *http.Request) {
@@ -94,7 +231,10 @@ func (s *Server) handleForceHeating(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	log.Info().Msgf("Forcing heating for %d minutes", duration_int)
+	after := map[string]any{"minutes": duration_int}
+	before := s.previousValues(map[string]any{"heating_end_time": nil})
 	s.climate.RunFor(duration_int)
+	s.recordAudit(r, before, after, nil)
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -109,10 +249,23 @@ func (s *Server) handleSet(w http.ResponseWriter, r *http.Request) {
 
 	log.Debug().Msgf("Received set request: %+v", state)
 
+	after := map[string]any{}
+	if state.Mode != "" {
+		after["mode"] = state.Mode
+	}
+	if state.TargetTemperature > 0 {
+		after["target_temperature"] = state.TargetTemperature
+	}
+	if state.HWTargetTemp > 0 {
+		after["hw_target_temp"] = state.HWTargetTemp
+	}
+	before := s.previousValues(after)
+
 	if state.Mode != "" {
 		log.Info().Msgf("Setting mode to %s", state.Mode)
 		if err := s.climate.SetMode(state.Mode); err != nil {
 			log.Error().Err(err).Msgf("Failed to set mode to %s", state.Mode)
+			s.recordAudit(r, before, after, err)
 			http.Error(w, "Failed to set mode", http.StatusInternalServerError)
 			return
 		}
@@ -124,6 +277,7 @@ func (s *Server) handleSet(w http.ResponseWriter, r *http.Request) {
 		log.Info().Msgf("Setting target temperature to %f", state.TargetTemperature)
 		if err := s.climate.SetTargetTemperature(state.TargetTemperature); err != nil {
 			log.Error().Err(err).Msgf("Failed to set target temperature to %f", state.TargetTemperature)
+			s.recordAudit(r, before, after, err)
 			http.Error(w, "Failed to set target temperature", http.StatusInternalServerError)
 			return
 		}
@@ -135,6 +289,7 @@ func (s *Server) handleSet(w http.ResponseWriter, r *http.Request) {
 		log.Info().Msgf("Setting hot water target temperature to %d", state.HWTargetTemp)
 		if err := s.climate.SetHWTargetTemp(state.HWTargetTemp); err != nil {
 			log.Error().Err(err).Msgf("Failed to set hot water target temperature to %d", state.HWTargetTemp)
+			s.recordAudit(r, before, after, err)
 			http.Error(w, "Failed to set hot water target temperature", http.StatusInternalServerError)
 			return
 		}
@@ -142,34 +297,62 @@ func (s *Server) handleSet(w http.ResponseWriter, r *http.Request) {
 		log.Debug().Msgf("Hot water target temperature not set")
 	}
 
+	if len(after) > 0 {
+		s.recordAudit(r, before, after, nil)
+	}
 	w.WriteHeader(http.StatusOK)
 }
 
+// handleOverride sets temperatures, by default as manual override which expires after ttl minutes,
+// source=http records pushed sensor reading instead. All parameters are checked before any is applied.
 func (s *Server) handleOverride(w http.ResponseWriter, r *http.Request) {
-	inside := r.URL.Query().Get("inside_temp")
-	if inside != "" {
-		log.Info().Msgf("Overriding inside temperature to %s", inside)
-		inside_float, err := strconv.ParseFloat(inside, 64)
-		if err != nil {
-			log.Error().Err(err).Msgf("Failed to parse inside temperature: %s", inside)
-			http.Error(w, "Invalid inside temperature", http.StatusBadRequest)
-			return
-		}
-		s.climate.SetInsideOverride(inside_float) // TODO: get value from request
-	}
-	outside := r.URL.Query().Get("outside_temp")
-	if outside != "" {
-		log.Info().Msgf("Overriding outside temperature to %s", outside)
-		outside_float, err := strconv.ParseFloat(outside, 64)
-		if err != nil {
-			log.Error().Err(err).Msgf("Failed to parse outside temperature: %s", outside)
-			http.Error(w, "Invalid outside temperature", http.StatusBadRequest)
+	query := r.URL.Query()
+	override := Override{Source: query.Get("source")}
+	if override.Source == "" {
+		override.Source = climate.SOURCE_OVERRIDE
+	}
+	if override.Source != climate.SOURCE_OVERRIDE && override.Source != climate.SOURCE_HTTP {
+		http.Error(w, "Invalid source", http.StatusBadRequest)
+		return
+	}
+	if value := query.Get("ttl"); value != "" {
+		minutes, err := strconv.ParseFloat(value, 64)
+		if err != nil || minutes <= 0 {
+			http.Error(w, "Invalid ttl", http.StatusBadRequest)
 			return
 		}
-		s.climate.SetOutsideOverride(outside_float)
+		override.TTLMinutes = minutes
+	}
+
+	var ok bool
+	if override.InsideTemp, ok = parseOptional(query, "inside_temp"); !ok {
+		http.Error(w, "Invalid inside temperature", http.StatusBadRequest)
+		return
 	}
+	if override.OutsideTemp, ok = parseOptional(query, "outside_temp"); !ok {
+		http.Error(w, "Invalid outside temperature", http.StatusBadRequest)
+		return
+	}
+	if override.WindSpeed, ok = parseOptional(query, "wind_speed"); !ok || (override.WindSpeed != nil && *override.WindSpeed < 0) {
+		http.Error(w, "Invalid wind speed", http.StatusBadRequest)
+		return
+	}
+	if override.Irradiance, ok = parseOptional(query, "irradiance"); !ok || (override.Irradiance != nil && *override.Irradiance < 0) {
+		http.Error(w, "Invalid irradiance", http.StatusBadRequest)
+		return
+	}
+	forceHeating := query.Get("force_heating")
 
-	forceHeating := r.URL.Query().Get("force_heating")
+	after := overrideValues(override)
+	switch forceHeating {
+	case "1":
+		after["force_heating"] = true
+	case "0":
+		after["force_heating"] = false
+	}
+	before := s.previousValues(after)
+
+	s.applyOverride(override)
 	switch forceHeating {
 	case "1":
 		log.Info().Msg("Forcing heating ON")
@@ -178,21 +361,47 @@ func (s *Server) handleOverride(w http.ResponseWriter, r *http.Request) {
 		log.Info().Msg("Forcing heating OFF")
 		s.climate.StopHeating()
 	}
-
+	s.auditOverride(r, before, after, override)
 	w.WriteHeader(http.StatusOK)
 }
 
+// parseOptional parses float query parameter, nil if it is not set
+func parseOptional(query url.Values, name string) (*float64, bool) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, true
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to parse %s: %s", name, value)
+		return nil, false
+	}
+	return &parsed, true
+}
+
 func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
-	if s.config.Name == "" {
-		s.config.Name = "Glow Worm Ultimate 3 35C"
+	state := s.getState()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(state); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
 	}
-	state := Get{
+}
+
+func (s *Server) getState() Get {
+	name := s.config.Name
+	if name == "" {
+		name = "Glow Worm Ultimate 3 35C"
+	}
+	return Get{
 		Mode:              s.climate.GetMode(),
 		TargetTemperature: s.climate.GetTargetTemperature(),
 		HWTargetTemp:      s.climate.GetHWTargetTemp(),
 
 		Boiler: Boiler{
-			Name:      s.config.Name,
+			Name:      name,
 			Connected: s.climate.IsConnected(),
 			Error:     s.climate.GetError(),
 		},
@@ -211,15 +420,4 @@ func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
 		HeatLossBalance: s.climate.GetHeatLossBalance(),
 		Stat:            s.climate.GetStat(),
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(state); err != nil {
-		log.Error().Err(err).Msg("Failed to encode response")
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-		return
-	}
-}
-
-func (s *Server) Shutdown() {
-	s.climate.Shutdown()
 }
@@ -0,0 +1,121 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksimuk/ebus-climate/internal/climate"
+	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/ksimuk/ebus-climate/internal/vailant"
+)
+
+func TestServerLifecycle(t *testing.T) {
+	cfg := config.Config{Listen: "127.0.0.1", WebPort: 0}
+	c := &stubClimate{mode: vailant.MODE_HEATING, target: 20}
+	s := NewServer(cfg, c, nil)
+
+	// slow request is drained during shutdown
+	release := make(chan struct{})
+	started := make(chan struct{})
+	s.mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+
+	if err := s.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	base := fmt.Sprintf("http://%s", s.Addr())
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Start() }()
+
+	response, err := http.Get(base + "/ping")
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("ping failed: %v", err)
+	}
+	response.Body.Close()
+
+	c.ebusd = climate.HEALTH_FAILED
+	response, err = http.Get(base + "/readyz")
+	if err != nil || response.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected not ready with failed ebusd: %v", err)
+	}
+	response.Body.Close()
+	response, err = http.Get(base + "/healthz")
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Errorf("expected alive with failed ebusd: %v", err)
+	}
+	response.Body.Close()
+
+	slow := make(chan string, 1)
+	go func() {
+		response, err := http.Get(base + "/slow")
+		if err != nil {
+			slow <- err.Error()
+			return
+		}
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+		slow <- string(body)
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- s.Shutdown(ctx)
+	}()
+
+	// commands are rejected as soon as shutdown starts
+	for !s.draining.Load() {
+		time.Sleep(time.Millisecond)
+	}
+	for _, command := range []struct{ method, path string }{
+		{http.MethodPost, "/set"},
+		{http.MethodPatch, API_PREFIX + "/settings"},
+	} {
+		w := httptest.NewRecorder()
+		s.mux.ServeHTTP(w, httptest.NewRequest(command.method, command.path, strings.NewReader(`{"target_temperature": 21}`)))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected %s to be rejected while draining, got %d", command.path, w.Code)
+		}
+	}
+	close(release)
+	if body := <-slow; body != "done" {
+		t.Errorf("in-flight request was not drained: %s", body)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("shutdown failed: %v", err)
+	}
+	if err := <-stopped; err != nil {
+		t.Errorf("expected Start to return nil after shutdown, got %v", err)
+	}
+	if !c.shutdown {
+		t.Error("climate was not shut down")
+	}
+	if _, err := http.Get(base + "/ping"); err == nil {
+		t.Error("expected listener to be closed")
+	}
+}
+
+func TestOverrideInvalidParameter(t *testing.T) {
+	c := &stubClimate{mode: vailant.MODE_HEATING, target: 20}
+	s := NewServer(config.Config{}, c, nil)
+
+	w := httptest.NewRecorder()
+	s.handleOverride(w, httptest.NewRequest(http.MethodPost, "/override?inside_temp=19&wind_speed=-1", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if c.inside != 0 {
+		t.Errorf("expected nothing applied, got inside %f", c.inside)
+	}
+}
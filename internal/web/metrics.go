@@ -0,0 +1,74 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ksimuk/ebus-climate/internal/vailant"
+)
+
+const METRICS_PREFIX = "ebus_climate_"
+
+// metricsWriter writes Prometheus text exposition format
+type metricsWriter struct {
+	w io.Writer
+}
+
+func (m *metricsWriter) write(kind string, name string, help string, value float64) {
+	name = METRICS_PREFIX + name
+	fmt.Fprintf(m.w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", name, help, name, kind, name, value)
+}
+
+func (m *metricsWriter) gauge(name string, help string, value float64) {
+	m.write("gauge", name, help, value)
+}
+
+func (m *metricsWriter) counter(name string, help string, value float64) {
+	m.write("counter", name, help, value)
+}
+
+func boolValue(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	stat := s.climate.GetStat()
+	bus := s.climate.GetBusStats()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m := &metricsWriter{w: w}
+
+	m.gauge("flow_temperature_celsius", "Boiler flow temperature.", s.climate.GetFlowTemp())
+	m.gauge("return_temperature_celsius", "Boiler return temperature.", s.climate.GetReturnTemp())
+	m.gauge("inside_temperature_celsius", "Inside temperature.", s.climate.GetInsideTemp())
+	m.gauge("outside_temperature_celsius", "Outside temperature.", s.climate.GetOutsideTemp())
+	m.gauge("target_temperature_celsius", "Target inside temperature.", s.climate.GetTargetTemperature())
+	m.gauge("hot_water_target_temperature_celsius", "Target hot water temperature.", float64(s.climate.GetHWTargetTemp()))
+	m.gauge("heat_loss_balance_wh", "Heat loss balance.", s.climate.GetHeatLossBalance())
+	m.gauge("heat_loss_watts", "Current heat loss.", stat.CurrentHeatLoss)
+	m.gauge("water_pressure_bar", "Heating circuit water pressure.", stat.WaterPressure)
+	m.gauge("heating_relay_active", "Heating relay state, 1 when boiler is fired.", boolValue(s.climate.IsGasActive()))
+	m.gauge("heating_mode", "Heating mode, 1 when heating is enabled.", boolValue(s.climate.GetMode() == vailant.MODE_HEATING))
+
+	m.counter("cycles_total", "Number of burns since start.", float64(stat.Cycles))
+	m.counter("burn_seconds_total", "Burn time since start.", stat.BurnSeconds)
+	// boiler counters are -1 until first read, a counter must not go backwards
+	if stat.UsageHeating >= 0 {
+		m.counter("energy_heating_kwh_total", "Boiler heating energy counter (PrEnergySumHc1).", stat.UsageHeating)
+	}
+	if stat.UsageHotWater >= 0 {
+		m.counter("energy_hot_water_kwh_total", "Boiler hot water energy counter (PrEnergySumHwc1).", stat.UsageHotWater)
+	}
+	m.counter("consumption_heating_kwh_total", "Estimated heating consumption.", s.climate.GetConsumption())
+
+	m.gauge("kwh_per_degree_day", "Heating energy per degree-day of last finished day.", stat.KWhPerDegreeDay)
+	m.gauge("efficiency_regression", "1 when last finished day was less efficient than baseline.", boolValue(stat.EfficiencyRegression))
+
+	m.counter("ebusd_requests_total", "Requests sent to ebusd.", float64(bus.Requests))
+	m.counter("ebusd_request_errors_total", "Failed requests to ebusd.", float64(bus.Errors))
+	m.counter("ebusd_request_duration_seconds_total", "Total time spent in ebusd requests.", bus.LatencySeconds)
+}
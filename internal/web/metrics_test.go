@@ -0,0 +1,68 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ksimuk/ebus-climate/internal/climate"
+	"github.com/ksimuk/ebus-climate/internal/config"
+)
+
+// metricsClimate returns fixed stat for metrics output
+type metricsClimate struct {
+	stubClimate
+	stat climate.Stat
+}
+
+func (m *metricsClimate) GetStat() climate.Stat         { return m.stat }
+func (m *metricsClimate) GetBusStats() climate.BusStats { return climate.BusStats{Requests: 3} }
+func (m *metricsClimate) GetFlowTemp() float64          { return 45 }
+func (m *metricsClimate) GetReturnTemp() float64        { return 35 }
+func (m *metricsClimate) GetInsideTemp() float64        { return 20 }
+func (m *metricsClimate) GetOutsideTemp() float64       { return 5 }
+func (m *metricsClimate) GetHeatLossBalance() float64   { return 100 }
+func (m *metricsClimate) IsGasActive() bool             { return true }
+func (m *metricsClimate) GetConsumption() float64       { return 12 }
+
+func metrics(t *testing.T, stat climate.Stat) string {
+	c := &metricsClimate{stat: stat}
+	s := &Server{climate: c, config: config.Config{}}
+	w := httptest.NewRecorder()
+	s.handleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	return w.Body.String()
+}
+
+func TestMetrics(t *testing.T) {
+	out := metrics(t, climate.Stat{Cycles: 2, UsageHeating: 150.5, UsageHotWater: 40})
+	for _, line := range []string{
+		"# TYPE ebus_climate_flow_temperature_celsius gauge\n",
+		"ebus_climate_flow_temperature_celsius 45\n",
+		"ebus_climate_heating_relay_active 1\n",
+		"# TYPE ebus_climate_cycles_total counter\n",
+		"ebus_climate_cycles_total 2\n",
+		"ebus_climate_energy_heating_kwh_total 150.5\n",
+		"ebus_climate_energy_hot_water_kwh_total 40\n",
+		"ebus_climate_ebusd_requests_total 3\n",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("expected %q in output:\n%s", line, out)
+		}
+	}
+}
+
+func TestMetricsUnreadCounters(t *testing.T) {
+	out := metrics(t, climate.Stat{UsageHeating: -1, UsageHotWater: -1})
+	for _, name := range []string{"ebus_climate_energy_heating_kwh_total", "ebus_climate_energy_hot_water_kwh_total"} {
+		if strings.Contains(out, name) {
+			t.Errorf("expected %s to be skipped until read, got:\n%s", name, out)
+		}
+	}
+	if !strings.Contains(out, "ebus_climate_cycles_total 0\n") {
+		t.Errorf("expected other counters, got:\n%s", out)
+	}
+}
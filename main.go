@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/rs/zerolog"
@@ -11,7 +12,9 @@ import (
 	"syscall"
 
 	"github.com/akamensky/argparse"
+	"github.com/ksimuk/ebus-climate/internal/alert"
 	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/ksimuk/ebus-climate/internal/homeassistant"
 	"github.com/ksimuk/ebus-climate/internal/web"
 )
 
@@ -53,13 +56,40 @@ func main() {
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 
 	server := web.GetServer(*config)
+	scanner := startThermometers(config, server.Climate())
+	bridge := homeassistant.Start(config, server.Climate(), server.Audit())
+	alerts := alert.New(config, server.Climate())
+	if alerts != nil {
+		if topic := alert.MQTTTopic(config); topic != "" && bridge != nil {
+			alerts.AddSink(alert.NewMQTTSink(func(payload []byte) { bridge.Publish(topic, payload) }))
+		}
+		if scanner != nil {
+			alerts.SetBatteries(batteries(scanner))
+		}
+		alerts.Start()
+	}
 
 	go func() {
-		<-sigs
-		log.Info().Msg("Shutting down...")
-		server.Shutdown()
-		os.Exit(0)
+		if err := server.Start(); err != nil {
+			log.Fatal().Err(err).Msg("Web server failed")
+		}
 	}()
 
-	server.Start()
+	<-sigs
+	log.Info().Msg("Shutting down...")
+	// stop other command sources before draining web server
+	if alerts != nil {
+		alerts.Stop()
+	}
+	if bridge != nil {
+		bridge.Stop()
+	}
+	if scanner != nil {
+		scanner.Stop()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), web.SHUTDOWN_TIMEOUT)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Error().Err(err).Msg("Shutdown did not complete")
+	}
 }
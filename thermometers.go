@@ -0,0 +1,48 @@
+package main
+
+import (
+	bluetooththermostat "github.com/ksimuk/ebus-climate/internal/bluetooth_thermostat"
+	"github.com/ksimuk/ebus-climate/internal/climate"
+	"github.com/ksimuk/ebus-climate/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// startThermometers registers configured BLE sensors and connects them to climate inputs
+func startThermometers(cfg *config.Config, c climate.Climate) *bluetooththermostat.Scanner {
+	if len(cfg.Sensors) == 0 {
+		return nil
+	}
+	scanner := bluetooththermostat.NewScanner()
+	for _, sensor := range cfg.Sensors {
+		// every sensor is a source of its own, so a dead one expires while others report
+		source := climate.SensorSource(climate.SOURCE_BLE, sensor.MAC)
+		var callback func(bluetooththermostat.Reading)
+		switch sensor.Role {
+		case config.SENSOR_ROLE_INSIDE:
+			callback = func(r bluetooththermostat.Reading) { c.SetInsideTemp(source, r.Temp) }
+		case config.SENSOR_ROLE_OUTSIDE:
+			callback = func(r bluetooththermostat.Reading) { c.SetOutsideTemp(source, r.Temp) }
+		}
+		if _, err := scanner.Register(sensor, callback); err != nil {
+			log.Error().Err(err).Msgf("Failed to register sensor %s", sensor.MAC)
+		}
+	}
+	if err := scanner.Start(); err != nil {
+		log.Error().Err(err).Msg("Failed to start BLE scanner")
+	}
+	return scanner
+}
+
+// batteries returns battery levels of sensors by name
+func batteries(scanner *bluetooththermostat.Scanner) func() map[string]float64 {
+	return func() map[string]float64 {
+		levels := map[string]float64{}
+		for _, sensor := range scanner.Sensors() {
+			reading, updated := sensor.Last()
+			if !updated.IsZero() {
+				levels[sensor.Name()] = reading.Battery
+			}
+		}
+		return levels
+	}
+}